@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+)
+
+const batchMaxOperations = 100
+
+// movieValidationError fails a batch operation whose movie does not pass ValidateMovie.
+type movieValidationError map[string]string
+
+func (e movieValidationError) Error() string {
+	return "failed validation"
+}
+
+type batchMovieInput struct {
+	Title   string       `json:"title"`
+	Runtime data.Runtime `json:"runtime"`
+	Year    int32        `json:"year"`
+	Genres  []string     `json:"genres"`
+}
+
+// apply copies the fields that were provided onto movie and validates the result, treating zero
+// values as not provided just as PATCH /v1/movies/:id does.
+func (input *batchMovieInput) apply(movie *data.Movie) error {
+	if input.Title != "" {
+		movie.Title = input.Title
+	}
+	if input.Runtime != 0 {
+		movie.Runtime = input.Runtime
+	}
+	if input.Year != 0 {
+		movie.Year = input.Year
+	}
+	if input.Genres != nil {
+		movie.Genres = input.Genres
+	}
+
+	v := validator.NewValidator()
+	if data.ValidateMovie(v, movie); !v.IsValid() {
+		return movieValidationError(v.Errors)
+	}
+
+	return nil
+}
+
+func (app *application) batchMovies(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Atomic     *bool `json:"atomic"`
+		Operations []struct {
+			Op      string           `json:"op"`
+			ID      int64            `json:"id"`
+			Version int32            `json:"version"`
+			Movie   *batchMovieInput `json:"movie"`
+		} `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.NewValidator()
+	v.CheckAdd(len(input.Operations) >= 1, "operations", "must have at least one")
+	v.CheckAdd(
+		len(input.Operations) <= batchMaxOperations, "operations",
+		fmt.Sprintf("cannot have more than %d", batchMaxOperations),
+	)
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	atomic := input.Atomic == nil || *input.Atomic
+
+	// operations that are malformed fail on their own without touching the database, and in an
+	// atomic batch they stop the rest from running at all
+	ops := make([]*data.MovieOperation, len(input.Operations))
+	malformed := false
+	for i, in := range input.Operations {
+		op := &data.MovieOperation{Op: in.Op, ID: in.ID, Version: in.Version}
+		ops[i] = op
+
+		v := validator.NewValidator()
+		v.CheckAdd(
+			validator.ValueInList(in.Op, data.BatchCreate, data.BatchUpdate, data.BatchDelete), "op",
+			"must be one of create, update or delete",
+		)
+		v.CheckAdd(in.Version >= 0, "version", "must be a positive integer")
+
+		switch in.Op {
+		case data.BatchCreate:
+			v.CheckAdd(in.ID == 0, "id", "must not be provided")
+			v.CheckAdd(in.Version == 0, "version", "must not be provided")
+			v.CheckAdd(in.Movie != nil, "movie", "must be provided")
+		case data.BatchUpdate:
+			v.CheckAdd(in.ID > 0, "id", "must be provided")
+			v.CheckAdd(in.Movie != nil, "movie", "must be provided")
+		case data.BatchDelete:
+			v.CheckAdd(in.ID > 0, "id", "must be provided")
+			v.CheckAdd(in.Movie == nil, "movie", "must not be provided")
+		}
+
+		if !v.IsValid() {
+			op.Err = movieValidationError(v.Errors)
+			malformed = true
+			continue
+		}
+
+		if in.Movie != nil {
+			op.Apply = in.Movie.apply
+		}
+	}
+
+	var pending []*data.MovieOperation
+	for _, op := range ops {
+		switch {
+		case op.Err != nil:
+		case atomic && malformed:
+			op.Err = data.ErrBatchAborted
+		default:
+			pending = append(pending, op)
+		}
+	}
+
+	if len(pending) > 0 {
+		err = app.models.Movies.ExecBatch(r.Context(), pending, atomic)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.outbox.Notify()
+	}
+
+	results := make([]envelope, len(ops))
+	for i, op := range ops {
+		results[i] = app.batchResult(r, i, op)
+	}
+
+	err = app.writeResponse(w, r, batchStatus(results, atomic), envelope{
+		"batch": envelope{"atomic": atomic, "results": results},
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// batchStatus is the status of the whole batch: 200 when every operation succeeded, otherwise
+// the status of the operation that failed an atomic batch, or 207 for a batch that partly failed.
+func batchStatus(results []envelope, atomic bool) int {
+	status := http.StatusOK
+	for _, result := range results {
+		opStatus := result["status"].(int)
+		switch {
+		case opStatus < http.StatusBadRequest || opStatus == http.StatusFailedDependency:
+		case atomic:
+			status = opStatus
+		default:
+			status = http.StatusMultiStatus
+		}
+	}
+
+	return status
+}
+
+// batchResult reports the outcome of an operation with the status code and, for failures, the
+// problem details that the single movie endpoints respond with.
+func (app *application) batchResult(r *http.Request, index int, op *data.MovieOperation) envelope {
+	result := envelope{"index": index, "op": op.Op}
+	if op.ID != 0 {
+		result["id"] = op.ID
+	}
+
+	var status int
+	var validationErr movieValidationError
+	switch {
+	case op.Err == nil && op.Op == data.BatchCreate:
+		status = http.StatusCreated
+		result["movie"] = op.Movie
+		result["id"] = op.Movie.ID
+	case op.Err == nil && op.Op == data.BatchUpdate:
+		status = http.StatusOK
+		result["movie"] = op.Movie
+	case op.Err == nil:
+		status = http.StatusOK
+		result["message"] = "movie deleted successfully"
+	case errors.As(op.Err, &validationErr):
+		status = http.StatusBadRequest
+		result["error"] = newProblem(r, status, "failed_validation", map[string]string(validationErr))
+	case errors.Is(op.Err, data.ErrNoRecord):
+		status = http.StatusNotFound
+		result["error"] = newProblem(
+			r, status, "not_found", "the resource you requested for could not be found",
+		)
+	case errors.Is(op.Err, data.ErrEditConflic) && op.Version != 0:
+		status = http.StatusPreconditionFailed
+		result["error"] = newProblem(
+			r, status, "precondition_failed",
+			"the movie is no longer at the expected version, please fetch it and try again",
+		)
+	case errors.Is(op.Err, data.ErrEditConflic):
+		status = http.StatusConflict
+		result["error"] = newProblem(
+			r, status, "edit_conflict",
+			"an error occcured and your edit did not go through, please try again",
+		)
+	case errors.Is(op.Err, data.ErrBatchAborted):
+		status = http.StatusFailedDependency
+		result["error"] = newProblem(
+			r, status, "batch_aborted",
+			"the operation was not applied because another operation in the atomic batch failed",
+		)
+	default:
+		app.logError(r.Context(), op.Err, map[string]string{"batch_index": fmt.Sprint(index)})
+
+		status = http.StatusInternalServerError
+		result["error"] = newProblem(
+			r, status, "server_error",
+			"the server encountered and error and could not resolve your request",
+		)
+	}
+
+	result["status"] = status
+	return result
+}
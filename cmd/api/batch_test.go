@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+)
+
+func TestBatchResult(t *testing.T) {
+	movie := &data.Movie{ID: 7, Title: "Moana", Year: 2016, Version: 2}
+
+	tests := []struct {
+		name       string
+		op         *data.MovieOperation
+		wantStatus int
+		wantCode   string
+	}{
+		{"created", &data.MovieOperation{Op: data.BatchCreate, Movie: movie}, 201, ""},
+		{"updated", &data.MovieOperation{Op: data.BatchUpdate, ID: 7, Movie: movie}, 200, ""},
+		{"deleted", &data.MovieOperation{Op: data.BatchDelete, ID: 7}, 200, ""},
+		{
+			"failed validation",
+			&data.MovieOperation{
+				Op: data.BatchCreate, Err: movieValidationError{"title": "must be provided"},
+			},
+			http.StatusBadRequest,
+			"failed_validation",
+		},
+		{
+			"not found",
+			&data.MovieOperation{Op: data.BatchDelete, ID: 7, Err: data.ErrNoRecord},
+			http.StatusNotFound,
+			"not_found",
+		},
+		{
+			"stale version",
+			&data.MovieOperation{Op: data.BatchUpdate, ID: 7, Version: 1, Err: data.ErrEditConflic},
+			http.StatusPreconditionFailed,
+			"precondition_failed",
+		},
+		{
+			"edit conflict",
+			&data.MovieOperation{Op: data.BatchUpdate, ID: 7, Err: data.ErrEditConflic},
+			http.StatusConflict,
+			"edit_conflict",
+		},
+		{
+			"aborted",
+			&data.MovieOperation{Op: data.BatchDelete, ID: 7, Err: data.ErrBatchAborted},
+			http.StatusFailedDependency,
+			"batch_aborted",
+		},
+		{
+			"unexpected error",
+			&data.MovieOperation{Op: data.BatchDelete, ID: 7, Err: errors.New("connection reset")},
+			http.StatusInternalServerError,
+			"server_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{logger: jsonlog.NewLogger(io.Discard, jsonlog.LevelOff)}
+			r := httptest.NewRequest(http.MethodPost, "/v1/movies/batch", nil)
+
+			result := app.batchResult(r, 3, tt.op)
+
+			if result["index"] != 3 {
+				t.Errorf("got index %v; want 3", result["index"])
+			}
+			if result["status"] != tt.wantStatus {
+				t.Errorf("got status %v; want %d", result["status"], tt.wantStatus)
+			}
+
+			problem, failed := result["error"].(envelope)
+			if failed != (tt.wantCode != "") {
+				t.Fatalf("got error %v; want code %q", result["error"], tt.wantCode)
+			}
+			if failed && problem["code"] != tt.wantCode {
+				t.Errorf("got code %v; want %q", problem["code"], tt.wantCode)
+			}
+			if !failed && tt.op.Op != data.BatchDelete && result["movie"] != movie {
+				t.Errorf("got movie %v; want %v", result["movie"], movie)
+			}
+		})
+	}
+}
+
+func TestBatchStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		atomic   bool
+		want     int
+	}{
+		{"all succeeded", []int{201, 200, 200}, false, http.StatusOK},
+		{"all succeeded atomically", []int{201, 200}, true, http.StatusOK},
+		{"partly failed", []int{201, 404, 200}, false, http.StatusMultiStatus},
+		{"all failed", []int{400, 404}, false, http.StatusMultiStatus},
+		{"atomic failure", []int{424, 404, 424}, true, http.StatusNotFound},
+		{"atomic validation failure", []int{424, 400}, true, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]envelope, len(tt.statuses))
+			for i, status := range tt.statuses {
+				results[i] = envelope{"status": status}
+			}
+
+			if got := batchStatus(results, tt.atomic); got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBatchMoviesMalformedOperations runs the handler without a database, which works because
+// malformed operations fail before the batch reaches it.
+func TestBatchMoviesMalformedOperations(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantStatuses []int
+	}{
+		{
+			"atomic batch is aborted",
+			`{"operations":[
+				{"op":"delete","id":7},
+				{"op":"create","id":3,"movie":{"title":"Moana"}}
+			]}`,
+			http.StatusBadRequest,
+			[]int{http.StatusFailedDependency, http.StatusBadRequest},
+		},
+		{
+			"non-atomic batch reports each failure",
+			`{"atomic":false,"operations":[
+				{"op":"rename","id":7},
+				{"op":"update","id":7},
+				{"op":"delete","movie":{"title":"Moana"}}
+			]}`,
+			http.StatusMultiStatus,
+			[]int{http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			body := strings.NewReader(tt.body)
+			r := httptest.NewRequest(http.MethodPost, "/v1/movies/batch", body)
+			rr := httptest.NewRecorder()
+
+			app.batchMovies(rr, r)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d; want %d: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+
+			var response struct {
+				Batch struct {
+					Results []struct {
+						Index  int `json:"index"`
+						Status int `json:"status"`
+					} `json:"results"`
+				} `json:"batch"`
+			}
+			err := json.Unmarshal(rr.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results := response.Batch.Results
+			if len(results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results; want %d", len(results), len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				if results[i].Index != i || results[i].Status != want {
+					t.Errorf("got result %d with status %d; want %d", i, results[i].Status, want)
+				}
+			}
+		})
+	}
+}
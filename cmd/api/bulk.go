@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+)
+
+// readBulkQuery reads the ListMovies filters of a bulk request along with its dry_run flag and
+// confirm_count, which is required unless it is a dry run and -1 when it is not given.
+func (app *application) readBulkQuery(
+	r *http.Request, v *validator.Validator,
+) (q data.MovieQuery, dryRun bool, confirmCount int) {
+	qs := r.URL.Query()
+
+	q = app.readMovieQuery(qs, v)
+
+	dryRunValue := app.readString(qs, "dry_run", "false")
+	v.CheckAdd(validator.ValueInList(dryRunValue, "true", "false"), "dry_run", "must be a boolean")
+	dryRun = dryRunValue == "true"
+
+	confirmCount = app.readInt(qs, "confirm_count", -1, v)
+	if !dryRun {
+		v.CheckAdd(
+			confirmCount >= 0, "confirm_count",
+			"must be provided as the number of movies the filter matches, see dry_run=true",
+		)
+	}
+
+	return q, dryRun, confirmCount
+}
+
+func (app *application) bulkUpdateMovies(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Genres struct {
+			Add    []string `json:"add"`
+			Remove []string `json:"remove"`
+		} `json:"genres"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.NewValidator()
+	q, dryRun, confirmCount := app.readBulkQuery(r, v)
+
+	add, remove := input.Genres.Add, input.Genres.Remove
+	v.CheckAdd(len(add)+len(remove) > 0, "genres", "must add or remove at least one genre")
+	for key, genres := range map[string][]string{"genres.add": add, "genres.remove": remove} {
+		v.CheckAdd(validator.ListUnique(genres...), key, "cannot have duplicates")
+		v.CheckAdd(!validator.ValueInList("", genres...), key, "cannot contain empty genres")
+	}
+	for _, genre := range add {
+		v.CheckAdd(
+			!validator.ValueInList(genre, remove...), "genres.remove",
+			"cannot contain a genre that is also being added",
+		)
+	}
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// a movie that would be left with too few or too many genres stops the whole update, since
+	// applying it to only some of the movies the caller confirmed would be surprising
+	movies, err := app.models.Movies.BulkUpdate(r.Context(), q, confirmCount, dryRun,
+		func(movie *data.Movie) (bool, error) {
+			genres := slices.DeleteFunc(slices.Clone(movie.Genres), func(genre string) bool {
+				return slices.Contains(remove, genre)
+			})
+			for _, genre := range add {
+				if !slices.Contains(genres, genre) {
+					genres = append(genres, genre)
+				}
+			}
+			if slices.Equal(genres, movie.Genres) {
+				return false, nil
+			}
+			movie.Genres = genres
+
+			v := validator.NewValidator()
+			data.ValidateMovie(v, movie)
+			if message, ok := v.Errors["genres"]; ok {
+				return false, movieValidationError{
+					"genres": fmt.Sprintf("movie %d %s", movie.ID, message),
+				}
+			}
+
+			return true, nil
+		},
+	)
+	if err != nil {
+		app.bulkErrorResponse(w, r, err)
+		return
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	if !dryRun {
+		app.outbox.Notify()
+		app.logBulkChange(r, "movies bulk updated", ids)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{
+		"dry_run": dryRun,
+		"count":   len(movies),
+		"movies":  movies,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) bulkDeleteMovies(w http.ResponseWriter, r *http.Request) {
+	v := validator.NewValidator()
+	q, dryRun, confirmCount := app.readBulkQuery(r, v)
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ids, err := app.models.Movies.BulkDelete(r.Context(), q, confirmCount, dryRun)
+	if err != nil {
+		app.bulkErrorResponse(w, r, err)
+		return
+	}
+
+	if !dryRun {
+		app.outbox.Notify()
+		app.logBulkChange(r, "movies bulk deleted", ids)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{
+		"dry_run": dryRun,
+		"count":   len(ids),
+		"ids":     ids,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) bulkErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var countErr *data.CountMismatchError
+	var validationErr movieValidationError
+	switch {
+	case errors.As(err, &countErr):
+		app.confirmCountMismatchResponse(w, r, countErr)
+	case errors.Is(err, data.ErrBulkLimitExceeded):
+		app.bulkLimitExceededResponse(w, r)
+	case errors.As(err, &validationErr):
+		app.failedValidationResponse(w, r, validationErr)
+	default:
+		app.serverError(w, r, err)
+	}
+}
+
+// logBulkChange keeps an audit trail of who changed which movies through the bulk endpoints,
+// since unlike single edits they are not tied to a URL naming the movie.
+func (app *application) logBulkChange(r *http.Request, message string, ids []int64) {
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = strconv.FormatInt(id, 10)
+	}
+
+	app.logger.WithContext(r.Context()).PrintIfo(message, map[string]string{
+		"filter": r.URL.RawQuery,
+		"count":  strconv.Itoa(len(ids)),
+		"ids":    strings.Join(formatted, ","),
+	})
+}
@@ -3,16 +3,26 @@ package main
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
 )
 
 type contextKey string
 
-var userContextKey = contextKey("user")
+var (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
+	if !user.IsAnonymous() {
+		ctx = jsonlog.WithProperties(ctx, map[string]string{
+			"user_id": strconv.FormatInt(user.ID, 10),
+		})
+	}
 	return r.WithContext(ctx)
 }
 
@@ -24,3 +34,13 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+func contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Yusufdot101/greenlight/internal/mailer"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+var emailSamples = map[string]any{
+	"user_welcome": map[string]any{
+		"Name":            "Jane Doe",
+		"userID":          123,
+		"activationToken": "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU",
+	},
+}
+
+func (app *application) previewEmailHandler(w http.ResponseWriter, r *http.Request) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("template")
+
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	format := app.readString(qs, "format", "json")
+	locales := app.readAcceptLanguage(r)
+	if locale := app.readString(qs, "locale", ""); locale != "" {
+		locales = []string{locale}
+	}
+
+	v.CheckAdd(
+		validator.ValueInList(format, "json", "html", "text"), "format",
+		"must be one of json, html or text",
+	)
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	msg, locale, err := app.mailer.Render(name, locales, emailSamples[name])
+	if err != nil {
+		switch {
+		case errors.Is(err, mailer.ErrUnknownTemplate):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Language", locale)
+
+	switch format {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err = w.Write([]byte(msg.HTMLBody))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = w.Write([]byte(msg.PlainBody))
+	default:
+		err = app.writeResponse(
+			w, r, http.StatusOK, envelope{
+				"email": envelope{
+					"template":   name,
+					"locale":     locale,
+					"locales":    app.mailer.Locales(),
+					"subject":    msg.Subject,
+					"plain_body": msg.PlainBody,
+					"html_body":  msg.HTMLBody,
+				},
+			},
+		)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Yusufdot101/greenlight/internal/msgpack"
+)
+
+var errNotEncodable = errors.New("response cannot be represented in the requested format")
+
+// errUnsupportedMediaType is returned by readJSON for bodies it cannot decode, and turned into a
+// 415 response by badRequestResponse.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// encoder writes a response envelope in one representation. All of them work from the JSON form
+// of the envelope, so field names and value formats such as the runtime are the same whichever
+// one a client asks for.
+type encoder struct {
+	contentType string
+	encode      func(w io.Writer, message envelope, pretty bool) error
+}
+
+// encoders are listed in order of preference for clients that accept several equally.
+var encoders = []encoder{
+	{contentType: "application/json", encode: encodeJSON},
+	{contentType: "application/xml", encode: encodeXML},
+	{contentType: "application/msgpack", encode: encodeMsgpack},
+	{contentType: "text/csv", encode: encodeCSV},
+}
+
+// acceptableEncoders returns the encoders the Accept header allows, best first.
+func acceptableEncoders(accept string) []encoder {
+	type ranked struct {
+		encoder encoder
+		quality float64
+	}
+
+	var acceptable []ranked
+	for _, encoder := range encoders {
+		quality := acceptQuality(accept, encoder.contentType)
+		if quality > 0 {
+			acceptable = append(acceptable, ranked{encoder: encoder, quality: quality})
+		}
+	}
+
+	slices.SortStableFunc(acceptable, func(a, b ranked) int {
+		return cmp.Compare(b.quality, a.quality)
+	})
+
+	sorted := make([]encoder, len(acceptable))
+	for i, ranked := range acceptable {
+		sorted[i] = ranked.encoder
+	}
+
+	return sorted
+}
+
+func encodeJSON(w io.Writer, message envelope, pretty bool) error {
+	var JSON []byte
+	var err error
+	if pretty {
+		JSON, err = json.MarshalIndent(message, "", "\t")
+	} else {
+		JSON, err = json.Marshal(message)
+	}
+	if err != nil {
+		return err
+	}
+
+	JSON = append(JSON, '\n')
+
+	_, err = w.Write(JSON)
+	return err
+}
+
+// toGeneric converts message to the maps, slices and scalars encoding/json decodes into, with
+// numbers kept as json.Number.
+func toGeneric(message envelope) (any, error) {
+	JSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(JSON))
+	decoder.UseNumber()
+
+	var value any
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+func encodeMsgpack(w io.Writer, message envelope, pretty bool) error {
+	value, err := toGeneric(message)
+	if err != nil {
+		return err
+	}
+
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// encodeXML writes objects as elements named after their keys, and arrays as repeated <item>
+// elements, under a <response> root. Keys are sorted since JSON objects have no order.
+func encodeXML(w io.Writer, message envelope, pretty bool) error {
+	value, err := toGeneric(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if pretty {
+		encoder.Indent("", "\t")
+	}
+
+	err = encodeXMLElement(encoder, "response", value)
+	if err != nil {
+		return err
+	}
+
+	err = encoder.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func encodeXMLElement(encoder *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch value := value.(type) {
+	case map[string]any:
+		err := encoder.EncodeToken(start)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			err = encodeXMLElement(encoder, key, value[key])
+			if err != nil {
+				return err
+			}
+		}
+
+		return encoder.EncodeToken(start.End())
+	case []any:
+		err := encoder.EncodeToken(start)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range value {
+			err = encodeXMLElement(encoder, "item", item)
+			if err != nil {
+				return err
+			}
+		}
+
+		return encoder.EncodeToken(start.End())
+	case nil:
+		return encoder.EncodeElement("", start)
+	default:
+		return encoder.EncodeElement(fmt.Sprint(value), start)
+	}
+}
+
+// encodeCSV writes responses that hold a single list of objects, such as GET /v1/movies, as one
+// row per object. Anything else in the envelope, like the pagination metadata, is left out, and
+// lists of scalars are joined with "|" as the movie export does.
+func encodeCSV(w io.Writer, message envelope, pretty bool) error {
+	var key string
+	for k, value := range message {
+		if isObjectList(value) {
+			if key != "" {
+				return errNotEncodable
+			}
+			key = k
+		}
+	}
+	if key == "" {
+		return errNotEncodable
+	}
+
+	JSON, err := json.Marshal(message[key])
+	if err != nil {
+		return err
+	}
+
+	var rawRows []json.RawMessage
+	err = json.Unmarshal(JSON, &rawRows)
+	if err != nil {
+		return errNotEncodable
+	}
+
+	// the columns follow the field order of the first row, with any keys that only appear in
+	// later rows added at the end
+	var columns []string
+	for _, raw := range rawRows {
+		keys, err := objectKeys(raw)
+		if err != nil {
+			return errNotEncodable
+		}
+		for _, k := range keys {
+			if !slices.Contains(columns, k) {
+				columns = append(columns, k)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	err = writer.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range rawRows {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var row map[string]any
+		err = decoder.Decode(&row)
+		if err != nil {
+			return err
+		}
+
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i], err = csvField(row[column])
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// isObjectList reports whether value is a slice whose elements encode as JSON objects.
+func isObjectList(value any) bool {
+	JSON, err := json.Marshal(value)
+	if err != nil || len(JSON) == 0 || JSON[0] != '[' {
+		return false
+	}
+
+	var items []json.RawMessage
+	err = json.Unmarshal(JSON, &items)
+	if err != nil {
+		return false
+	}
+
+	for _, item := range items {
+		if len(item) == 0 || item[0] != '{' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, errNotEncodable
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func csvField(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case []any:
+		fields := make([]string, len(value))
+		for i, item := range value {
+			switch item.(type) {
+			case map[string]any, []any:
+				return "", errNotEncodable
+			}
+			fields[i] = fmt.Sprint(item)
+		}
+		return strings.Join(fields, "|"), nil
+	case map[string]any:
+		return "", errNotEncodable
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
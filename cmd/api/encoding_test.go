@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yusufdot101/greenlight/internal/msgpack"
+)
+
+var (
+	testMovie = map[string]any{
+		"id":      1,
+		"title":   "Moana",
+		"year":    2016,
+		"runtime": "107 mins",
+		"genres":  []string{"animation", "adventure"},
+	}
+	testMovieList = envelope{"movies": []any{testMovie}, "metadata": map[string]any{"total": 1}}
+)
+
+func TestWriteResponseNegotiation(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		message         envelope
+		wantStatus      int
+		wantContentType string
+	}{
+		{"no accept header", "", envelope{"movie": testMovie}, 200, "application/json"},
+		{"any type", "*/*", envelope{"movie": testMovie}, 200, "application/json"},
+		{
+			"msgpack",
+			"application/msgpack",
+			envelope{"movie": testMovie},
+			200,
+			"application/msgpack",
+		},
+		{
+			"quality picks msgpack",
+			"application/json;q=0.5, application/msgpack",
+			envelope{"movie": testMovie},
+			200,
+			"application/msgpack",
+		},
+		{
+			"specific range overrides wildcard",
+			"*/*, application/json;q=0",
+			envelope{"movie": testMovie},
+			200,
+			"application/xml",
+		},
+		{"csv list", "text/csv", testMovieList, 200, "text/csv"},
+		{
+			"csv falls through for a single movie",
+			"text/csv, application/json;q=0.1",
+			envelope{"movie": testMovie},
+			200,
+			"application/json",
+		},
+		{
+			"csv only for a single movie",
+			"text/csv",
+			envelope{"movie": testMovie},
+			http.StatusNotAcceptable,
+			"application/problem+json",
+		},
+		{
+			"unavailable type",
+			"image/png",
+			envelope{"movie": testMovie},
+			http.StatusNotAcceptable,
+			"application/problem+json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/movies/1", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			err := app.writeResponse(rr, r, http.StatusOK, tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("got Content-Type %q; want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestWriteResponseMsgpackRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		message envelope
+	}{
+		{"single movie", envelope{"movie": testMovie}},
+		{"movie list", testMovieList},
+		{"empty list", envelope{"movies": []any{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			body := func(accept string) []byte {
+				r := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+				r.Header.Set("Accept", accept)
+				rr := httptest.NewRecorder()
+
+				err := app.writeResponse(rr, r, http.StatusOK, tt.message)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return rr.Body.Bytes()
+			}
+
+			value, err := msgpack.Unmarshal(body("application/msgpack"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := bytes.TrimSpace(body("application/json"))
+			if !bytes.Equal(got, want) {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestReadJSONMsgpack(t *testing.T) {
+	type input struct {
+		Title  string   `json:"title"`
+		Year   int32    `json:"year"`
+		Genres []string `json:"genres"`
+	}
+
+	encode := func(t *testing.T, value any) []byte {
+		t.Helper()
+
+		b, err := msgpack.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	movie := map[string]any{
+		"title":  "Moana",
+		"year":   json.Number("2016"),
+		"genres": []any{"animation", "adventure"},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     string
+	}{
+		{"msgpack", "application/msgpack", encode(t, movie), ""},
+		{"x-msgpack", "application/x-msgpack", encode(t, movie), ""},
+		{"vnd.msgpack", "application/vnd.msgpack", encode(t, movie), ""},
+		{
+			"unknown key",
+			"application/msgpack",
+			encode(t, map[string]any{"title": "Moana", "rating": "PG"}),
+			`body contains unknown key: "rating"`,
+		},
+		{
+			"wrong type",
+			"application/msgpack",
+			encode(t, map[string]any{"year": "2016"}),
+			"body contains invaild type for field: year",
+		},
+		{"empty body", "application/msgpack", nil, "body cannot be empty"},
+		{"badly formed", "application/msgpack", []byte{0xd2, 0x00}, "badly formed MessagePack"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/movies", bytes.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			var dst input
+			err := app.readJSON(httptest.NewRecorder(), r, &dst)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v; want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := input{Title: "Moana", Year: 2016, Genres: []string{"animation", "adventure"}}
+			if !reflect.DeepEqual(dst, want) {
+				t.Errorf("got %+v; want %+v", dst, want)
+			}
+		})
+	}
+}
+
+func TestReadJSONUnsupportedMediaType(t *testing.T) {
+	app := &application{}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/movies", strings.NewReader("title=Moana"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var dst struct{}
+	err := app.readJSON(httptest.NewRecorder(), r, &dst)
+	if !errors.Is(err, errUnsupportedMediaType) {
+		t.Errorf("got error %v; want errUnsupportedMediaType", err)
+	}
+}
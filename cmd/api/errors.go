@@ -1,79 +1,223 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
 )
 
-func (app *application) logError(err error, properties map[string]string) {
-	app.logger.PrintError(err, properties)
+type problemField struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+const problemTypePrefix = "urn:greenlight:problem:"
+
+func (app *application) logError(ctx context.Context, err error, properties map[string]string) {
+	app.logger.WithContext(ctx).PrintError(err, properties)
+}
+
+// errorResponse sends an RFC 9457 application/problem+json response, unless the client's Accept
+// header prefers plain application/json, in which case it gets the legacy {"error": message}
+// body. message is either a string or, for validation errors, a map of field names to messages.
+func (app *application) errorResponse(
+	w http.ResponseWriter, r *http.Request, statusCode int, code string, message any,
+) {
+	if negotiate(r.Header.Get("Accept"), "application/problem+json", "application/json") ==
+		"application/json" {
+		app.writeError(w, r, statusCode, "application/json", envelope{"error": message})
+		return
+	}
+
+	problem := newProblem(r, statusCode, code, message)
+	app.writeError(w, r, statusCode, "application/problem+json", problem)
 }
 
-func (app *application) errorResponse(w http.ResponseWriter, statusCode int, message any) {
-	err := app.writeJSON(w, statusCode, envelope{"error": message})
+// newProblem builds the problem details object for an error. code is stable and machine
+// readable, and the problem type is derived from it, so clients should switch on either of them
+// rather than on the title or detail.
+func newProblem(r *http.Request, statusCode int, code string, message any) envelope {
+	problem := envelope{
+		"type":     problemTypePrefix + strings.ReplaceAll(code, "_", "-"),
+		"title":    http.StatusText(statusCode),
+		"status":   statusCode,
+		"instance": contextGetRequestID(r),
+		"code":     code,
+	}
+
+	switch message := message.(type) {
+	case map[string]string:
+		fields := make([]problemField, 0, len(message))
+		for field, fieldMessage := range message {
+			fields = append(fields, problemField{Field: field, Message: fieldMessage})
+		}
+		slices.SortFunc(fields, func(a, b problemField) int {
+			return strings.Compare(a.Field, b.Field)
+		})
+
+		problem["detail"] = "one or more fields failed validation"
+		problem["errors"] = fields
+	default:
+		problem["detail"] = fmt.Sprint(message)
+	}
+
+	return problem
+}
+
+// writeError always encodes as JSON, whatever representation the client asked for, so that
+// errors, including the 406 for an Accept header we cannot satisfy, have a single format.
+func (app *application) writeError(
+	w http.ResponseWriter, r *http.Request, statusCode int, contentType string, message envelope,
+) {
+	var buf bytes.Buffer
+	err := encodeJSON(&buf, message, r.URL.Query().Has("pretty"))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
 }
 
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logError(err, map[string]string{"method": r.Method})
+	tracing.SpanFromContext(r.Context()).RecordError(err)
+	app.logError(r.Context(), err, nil)
 
 	message := "the server encountered and error and could not resolve your request"
-	app.errorResponse(w, http.StatusInternalServerError, message)
+	app.errorResponse(w, r, http.StatusInternalServerError, "server_error", message)
 }
 
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the resource you requested for could not be found"
-	app.errorResponse(w, http.StatusNotFound, message)
+	app.errorResponse(w, r, http.StatusNotFound, "not_found", message)
 }
 
 func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("the %s method is not allowed for this resource", r.Method)
-	app.errorResponse(w, http.StatusMethodNotAllowed, message)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message)
 }
 
-func (app *application) badRequestResponse(w http.ResponseWriter, err error) {
-	app.errorResponse(w, http.StatusBadRequest, err.Error())
+// badRequestResponse reports err as a 400, except for errUnsupportedMediaType from readJSON,
+// which gets a 415.
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	app.errorResponse(w, r, http.StatusBadRequest, "bad_request", err.Error())
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, err map[string]string) {
-	app.errorResponse(w, http.StatusBadRequest, err)
+func (app *application) failedValidationResponse(
+	w http.ResponseWriter, r *http.Request, err map[string]string,
+) {
+	app.errorResponse(w, r, http.StatusBadRequest, "failed_validation", err)
 }
 
-func (app *application) editConflictResponse(w http.ResponseWriter) {
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "an error occcured and your edit did not go through, please try again"
-	app.errorResponse(w, http.StatusConflict, message)
+	app.errorResponse(w, r, http.StatusConflict, "edit_conflict", message)
 }
 
-func (app *application) rateLimitExceededResponse(w http.ResponseWriter) {
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
-	app.errorResponse(w, http.StatusTooManyRequests, message)
+	app.errorResponse(w, r, http.StatusTooManyRequests, "rate_limit_exceeded", message)
 }
 
-func (app *application) invalidCredentialsResponse(w http.ResponseWriter) {
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invaild credentials"
-	app.errorResponse(w, http.StatusBadRequest, message)
+	app.errorResponse(w, r, http.StatusBadRequest, "invalid_credentials", message)
 }
 
-func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter) {
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 
 	message := "token invalid or missing"
-	app.errorResponse(w, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_authentication_token", message)
 }
 
-func (app *application) authenticationRequiredResponse(w http.ResponseWriter) {
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
-	app.errorResponse(w, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "authentication_required", message)
 }
 
-func (app *application) inactiveAccountResponse(w http.ResponseWriter) {
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account must be activated to access this resource"
-	app.errorResponse(w, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "inactive_account", message)
 }
 
-func (app *application) notPermittedResponse(w http.ResponseWriter) {
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
-	app.errorResponse(w, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "not_permitted", message)
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf(
+		"the %q content type is not supported for this resource", r.Header.Get("Content-Type"),
+	)
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type", message)
+}
+
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf(
+		"none of the media types in %q are available for this resource", r.Header.Get("Accept"),
+	)
+	app.errorResponse(w, r, http.StatusNotAcceptable, "not_acceptable", message)
+}
+
+func (app *application) jobNotRetryableResponse(w http.ResponseWriter, r *http.Request) {
+	message := "only pending or dead jobs can be retried"
+	app.errorResponse(w, r, http.StatusConflict, "job_not_retryable", message)
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has changed since you last fetched it, please fetch it and try again"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, "precondition_failed", message)
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this request must include an If-Match header with the resource's current ETag"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, "precondition_required", message)
+}
+
+func (app *application) idempotencyKeyReusedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this Idempotency-Key has already been used for a different request"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused", message)
+}
+
+func (app *application) idempotencyKeyInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+
+	message := "a request with this Idempotency-Key is still being processed, please try again"
+	app.errorResponse(w, r, http.StatusConflict, "idempotency_key_in_progress", message)
+}
+
+func (app *application) confirmCountMismatchResponse(
+	w http.ResponseWriter, r *http.Request, err *data.CountMismatchError,
+) {
+	message := fmt.Sprintf(
+		"the filter matches %d movies but confirm_count is %d, check the filter with dry_run=true",
+		err.Actual, err.Expected,
+	)
+	app.errorResponse(w, r, http.StatusPreconditionFailed, "confirm_count_mismatch", message)
+}
+
+func (app *application) bulkLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf(
+		"the filter matches more than %d movies, please narrow it down", data.BulkMaxMovies,
+	)
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, "bulk_limit_exceeded", message)
+}
+
+func (app *application) patchTestFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusConflict, "patch_test_failed", err.Error())
 }
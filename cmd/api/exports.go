@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+)
+
+const (
+	exportFlushEvery   = 100
+	exportWriteTimeout = 30 * time.Second
+)
+
+type movieExporter interface {
+	begin() error
+	write(movie *data.Movie) error
+	end() error
+}
+
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExporter) begin() error { return nil }
+
+func (e *ndjsonExporter) write(movie *data.Movie) error { return e.encoder.Encode(movie) }
+
+func (e *ndjsonExporter) end() error { return nil }
+
+type jsonExporter struct {
+	w       io.Writer
+	written int
+}
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, `{"movies":[`)
+	return err
+}
+
+func (e *jsonExporter) write(movie *data.Movie) error {
+	JSON, err := json.Marshal(movie)
+	if err != nil {
+		return err
+	}
+
+	if e.written > 0 {
+		JSON = append([]byte{','}, JSON...)
+	}
+	e.written++
+
+	_, err = e.w.Write(JSON)
+	return err
+}
+
+func (e *jsonExporter) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvExporter) begin() error {
+	return e.writer.Write([]string{"id", "title", "runtime", "year", "genres", "version"})
+}
+
+func (e *csvExporter) write(movie *data.Movie) error {
+	err := e.writer.Write([]string{
+		strconv.FormatInt(movie.ID, 10),
+		movie.Title,
+		strconv.Itoa(int(movie.Runtime)),
+		strconv.Itoa(int(movie.Year)),
+		strings.Join(movie.Genres, "|"),
+		strconv.Itoa(int(movie.Version)),
+	})
+	if err != nil {
+		return err
+	}
+
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExporter) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (app *application) exportMovies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	q := app.readMovieQuery(qs, v)
+	format := app.readString(qs, "format", "ndjson")
+	filter := data.Filter{
+		Page:         1,
+		PageSize:     1,
+		Sort:         app.readString(qs, "sort", "id"),
+		SafeSortList: movieSafeSortList,
+	}
+
+	v.CheckAdd(
+		validator.ValueInList(format, "ndjson", "csv", "json"), "format",
+		"must be one of ndjson, csv or json",
+	)
+
+	if data.ValidateFilters(v, &filter); !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	stream := &exportStream{w: w, rc: http.NewResponseController(w)}
+	buffered := bufio.NewWriterSize(stream, 64*1024)
+
+	var exporter movieExporter
+	var contentType string
+	switch format {
+	case "csv":
+		exporter = &csvExporter{writer: csv.NewWriter(buffered)}
+		contentType = "text/csv"
+	case "json":
+		exporter = &jsonExporter{w: buffered}
+		contentType = "application/json"
+	default:
+		exporter = &ndjsonExporter{encoder: json.NewEncoder(buffered)}
+		contentType = "application/x-ndjson"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(
+		"Content-Disposition", fmt.Sprintf(`attachment; filename="movies.%s"`, format),
+	)
+
+	count := 0
+	err := exporter.begin()
+	if err == nil {
+		err = app.models.Movies.StreamMovies(
+			r.Context(), q, filter, func(movie *data.Movie) error {
+				err := exporter.write(movie)
+				if err != nil {
+					return err
+				}
+
+				count++
+				if count%exportFlushEvery == 0 {
+					return buffered.Flush()
+				}
+				return nil
+			},
+		)
+	}
+	if err == nil {
+		err = exporter.end()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+
+	if err != nil {
+		if !stream.started {
+			app.serverError(w, r, err)
+			return
+		}
+
+		app.logError(r.Context(), err, map[string]string{"exported": strconv.Itoa(count)})
+	}
+}
+
+// exportStream pushes every flushed chunk to the client straight away and extends the write
+// deadline as it goes, so that a long export is not cut off by the server's WriteTimeout while
+// a stalled client still is.
+type exportStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func (s *exportStream) Write(p []byte) (int, error) {
+	err := s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+
+	s.started = true
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	err = s.rc.Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+
+	return n, nil
+}
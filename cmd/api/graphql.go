@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type graphqlError struct {
+	message string
+	code    string
+	fields  map[string]string
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+func (e *graphqlError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.code}
+	if e.fields != nil {
+		extensions["errors"] = e.fields
+	}
+	return extensions
+}
+
+var (
+	errGraphQLAuthenticationRequired = &graphqlError{
+		message: "you must be authenticated to access this resource",
+		code:    "UNAUTHENTICATED",
+	}
+	errGraphQLInactiveAccount = &graphqlError{
+		message: "your user account must be activated to access this resource",
+		code:    "FORBIDDEN",
+	}
+	errGraphQLNotPermitted = &graphqlError{
+		message: "your user account doesn't have the necessary permissions to access this resource",
+		code:    "FORBIDDEN",
+	}
+	errGraphQLNotFound = &graphqlError{
+		message: "the resource you requested for could not be found",
+		code:    "NOT_FOUND",
+	}
+	errGraphQLEditConflict = &graphqlError{
+		message: "an error occcured and your edit did not go through, please try again",
+		code:    "EDIT_CONFLICT",
+	}
+)
+
+func graphqlValidationError(fields map[string]string) error {
+	return &graphqlError{message: "failed validation", code: "FAILED_VALIDATION", fields: fields}
+}
+
+func (app *application) graphqlServerError(ctx context.Context, err error) error {
+	app.logError(ctx, err, map[string]string{"api": "graphql"})
+
+	return &graphqlError{
+		message: "the server encountered and error and could not resolve your request",
+		code:    "INTERNAL_SERVER_ERROR",
+	}
+}
+
+// graphqlCaller carries the authenticated user through the resolvers of a single request and
+// loads their permissions at most once however many fields ask for them.
+type graphqlCaller struct {
+	user        *data.User
+	once        sync.Once
+	permissions data.Permissions
+	err         error
+}
+
+var graphqlCallerContextKey = contextKey("graphql_caller")
+
+func graphqlCallerFrom(ctx context.Context) *graphqlCaller {
+	caller, ok := ctx.Value(graphqlCallerContextKey).(*graphqlCaller)
+	if !ok {
+		panic("missing graphql caller in request context")
+	}
+
+	return caller
+}
+
+func (caller *graphqlCaller) loadPermissions(
+	ctx context.Context, app *application,
+) (data.Permissions, error) {
+	caller.once.Do(func() {
+		if caller.user.IsAnonymous() {
+			caller.permissions = data.Permissions{}
+			return
+		}
+
+		caller.permissions, caller.err = app.models.Permissions.GellAllForUser(
+			ctx, caller.user.ID,
+		)
+		if caller.err != nil {
+			caller.err = app.graphqlServerError(ctx, caller.err)
+		}
+	})
+
+	return caller.permissions, caller.err
+}
+
+// graphqlPermission is the resolver counterpart of requirePermission.
+func (app *application) graphqlPermission(
+	permission string, resolve graphql.FieldResolveFn,
+) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		caller := graphqlCallerFrom(p.Context)
+		if caller.user.IsAnonymous() {
+			return nil, errGraphQLAuthenticationRequired
+		}
+		if !caller.user.Activated {
+			return nil, errGraphQLInactiveAccount
+		}
+
+		permissions, err := caller.loadPermissions(p.Context, app)
+		if err != nil {
+			return nil, err
+		}
+		if !permissions.Include(permission) {
+			return nil, errGraphQLNotPermitted
+		}
+
+		return resolve(p)
+	}
+}
+
+func (app *application) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+		Extensions    map[string]any `json:"extensions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if strings.TrimSpace(input.Query) == "" {
+		app.failedValidationResponse(w, r, map[string]string{"query": "must be provided"})
+		return
+	}
+
+	// documents that fail to parse are left for graphql.Do to report in the usual format
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(input.Query)}),
+	})
+	if err == nil {
+		cost := measureGraphQL(document, input.Variables)
+
+		var limitErr *graphqlError
+		switch {
+		// graphql-go recurses forever validating a fragment that spreads itself, so these
+		// have to be turned away before the document reaches it
+		case cost.cycle != "":
+			limitErr = &graphqlError{
+				message: fmt.Sprintf("cannot spread fragment %q within itself", cost.cycle),
+				code:    "GRAPHQL_VALIDATION_FAILED",
+			}
+		case cost.depth > app.config.graphql.maxDepth:
+			limitErr = &graphqlError{
+				message: fmt.Sprintf(
+					"query depth %d exceeds the limit of %d", cost.depth, app.config.graphql.maxDepth,
+				),
+				code: "QUERY_TOO_DEEP",
+			}
+		case cost.complexity > app.config.graphql.maxComplexity:
+			limitErr = &graphqlError{
+				message: fmt.Sprintf(
+					"query complexity %d exceeds the limit of %d",
+					cost.complexity, app.config.graphql.maxComplexity,
+				),
+				code: "QUERY_TOO_COMPLEX",
+			}
+		}
+
+		if limitErr != nil {
+			err = app.writeResponse(w, r, http.StatusBadRequest, envelope{
+				"errors": []envelope{
+					{"message": limitErr.Error(), "extensions": limitErr.Extensions()},
+				},
+			})
+			if err != nil {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+	}
+
+	caller := &graphqlCaller{user: app.contextGetUser(r)}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         app.graphql,
+		RequestString:  input.Query,
+		VariableValues: input.Variables,
+		OperationName:  input.OperationName,
+		Context:        context.WithValue(r.Context(), graphqlCallerContextKey, caller),
+	})
+
+	env := envelope{"data": result.Data}
+	if result.HasErrors() {
+		env["errors"] = result.Errors
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// graphqlListSizes names the fields that return a page of results and the argument that sets
+// how many, so their selections can be charged once per item.
+var graphqlListSizes = map[string]struct {
+	arg          string
+	defaultValue int
+}{
+	"movies": {arg: "page_size", defaultValue: 20},
+}
+
+type graphqlCost struct {
+	depth      int
+	complexity int
+	cycle      string
+}
+
+type graphqlMeasurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func measureGraphQL(document *ast.Document, variables map[string]any) graphqlCost {
+	m := graphqlMeasurer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var total graphqlCost
+	for _, definition := range document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			cost := m.selectionSet(operation.SelectionSet, map[string]bool{})
+			total.depth = max(total.depth, cost.depth)
+			total.complexity += cost.complexity
+			if total.cycle == "" {
+				total.cycle = cost.cycle
+			}
+		}
+	}
+
+	return total
+}
+
+func (m graphqlMeasurer) selectionSet(set *ast.SelectionSet, visiting map[string]bool) graphqlCost {
+	var cost graphqlCost
+	if set == nil {
+		return cost
+	}
+
+	for _, selection := range set.Selections {
+		var child graphqlCost
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+
+			child = m.selectionSet(selection.SelectionSet, visiting)
+			child.depth++
+			child.complexity = 1 + child.complexity*m.listSize(selection)
+		case *ast.InlineFragment:
+			child = m.selectionSet(selection.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			if visiting[name] {
+				cost.cycle = name
+				continue
+			}
+
+			fragment, ok := m.fragments[name]
+			if !ok {
+				continue
+			}
+
+			visiting[name] = true
+			child = m.selectionSet(fragment.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+
+		cost.depth = max(cost.depth, child.depth)
+		cost.complexity += child.complexity
+		if cost.cycle == "" {
+			cost.cycle = child.cycle
+		}
+	}
+
+	return cost
+}
+
+func (m graphqlMeasurer) listSize(field *ast.Field) int {
+	size, ok := graphqlListSizes[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != size.arg {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			n, err := strconv.Atoi(value.Value)
+			if err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[value.Name.Value].(float64); ok && n > 0 {
+				return int(n)
+			}
+		}
+	}
+
+	return size.defaultValue
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+	"github.com/graphql-go/graphql"
+)
+
+func (app *application) newGraphQLSchema() (graphql.Schema, error) {
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"year":  &graphql.Field{Type: graphql.Int},
+			"runtime": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Runtime in minutes.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return int(p.Source.(*data.Movie).Runtime), nil
+				},
+			},
+			"genres":  &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*data.Movie).CreatedAt, nil
+				},
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"current_page":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"page_size":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"total_records": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"last_page":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"has_next_page": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					metadata := p.Source.(*data.Metadata)
+					return metadata.CurrentPage < metadata.LastPage, nil
+				},
+			},
+		},
+	})
+
+	movieConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MovieConnection",
+		Fields: graphql.Fields{
+			"nodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(movieType))),
+			},
+			"page_info": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"activated":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"locale":     &graphql.Field{Type: graphql.String},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+			"permissions": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return graphqlCallerFrom(p.Context).loadPermissions(p.Context, app)
+				},
+			},
+		},
+	})
+
+	movieFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MovieFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"search":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"year":           &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"year_min":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"year_max":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"runtime_min":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"runtime_max":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"genres":         &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"genres_any":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"exclude_genres": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"created_after":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"created_before": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	movieInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MovieInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"runtime": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"year":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"genres":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: app.graphqlPermission("movies:read", app.resolveMovie),
+			},
+			"movies": &graphql.Field{
+				Type: graphql.NewNonNull(movieConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"filter":    &graphql.ArgumentConfig{Type: movieFilterType},
+					"page":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"page_size": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+					"sort":      &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "id"},
+				},
+				Resolve: app.graphqlPermission("movies:read", app.resolveMovies),
+			},
+			"me": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user := graphqlCallerFrom(p.Context).user
+					if user.IsAnonymous() {
+						return nil, nil
+					}
+					return user, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"create_movie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInputType)},
+				},
+				Resolve: app.graphqlPermission("movies:write", app.resolveCreateMovie),
+			},
+			"update_movie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInputType)},
+				},
+				Resolve: app.graphqlPermission("movies:write", app.resolveUpdateMovie),
+			},
+			"delete_movie": &graphql.Field{
+				Type: graphql.Int,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: app.graphqlPermission("movies:write", app.resolveDeleteMovie),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func (app *application) resolveMovie(p graphql.ResolveParams) (any, error) {
+	movie, err := app.models.Movies.GetByID(p.Context, int64(p.Args["id"].(int)))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, nil
+		default:
+			return nil, app.graphqlServerError(p.Context, err)
+		}
+	}
+
+	return movie, nil
+}
+
+func (app *application) resolveMovies(p graphql.ResolveParams) (any, error) {
+	// the filter shares its field names with the query string of GET /v1/movies, so it goes
+	// through the same parsing and validation
+	qs := url.Values{}
+	if filter, ok := p.Args["filter"].(map[string]any); ok {
+		for key, value := range filter {
+			switch value := value.(type) {
+			case []any:
+				values := make([]string, len(value))
+				for i, v := range value {
+					values[i] = fmt.Sprint(v)
+				}
+				qs.Set(key, strings.Join(values, ","))
+			case nil:
+			default:
+				qs.Set(key, fmt.Sprint(value))
+			}
+		}
+	}
+
+	v := validator.NewValidator()
+	movieQuery := app.readMovieQuery(qs, v)
+
+	filter := data.Filter{
+		Page:         p.Args["page"].(int),
+		PageSize:     p.Args["page_size"].(int),
+		Sort:         p.Args["sort"].(string),
+		SafeSortList: movieSafeSortList,
+	}
+	if data.ValidateFilters(v, &filter); !v.IsValid() {
+		return nil, graphqlValidationError(v.Errors)
+	}
+
+	movies, metadata, err := app.models.Movies.ListMovies(p.Context, movieQuery, filter)
+	if err != nil {
+		return nil, app.graphqlServerError(p.Context, err)
+	}
+
+	return map[string]any{"nodes": movies, "page_info": metadata}, nil
+}
+
+func (app *application) resolveCreateMovie(p graphql.ResolveParams) (any, error) {
+	movie := &data.Movie{}
+	applyMovieInput(movie, p.Args["input"].(map[string]any))
+
+	v := validator.NewValidator()
+	if data.ValidateMovie(v, movie); !v.IsValid() {
+		return nil, graphqlValidationError(v.Errors)
+	}
+
+	err := app.models.Movies.InsertMovie(p.Context, movie)
+	if err != nil {
+		return nil, app.graphqlServerError(p.Context, err)
+	}
+
+	app.outbox.Notify()
+	return movie, nil
+}
+
+func (app *application) resolveUpdateMovie(p graphql.ResolveParams) (any, error) {
+	movie, err := app.models.Movies.GetByID(p.Context, int64(p.Args["id"].(int)))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, errGraphQLNotFound
+		default:
+			return nil, app.graphqlServerError(p.Context, err)
+		}
+	}
+
+	if version, ok := p.Args["version"].(int); ok && int32(version) != movie.Version {
+		return nil, errGraphQLEditConflict
+	}
+
+	applyMovieInput(movie, p.Args["input"].(map[string]any))
+
+	v := validator.NewValidator()
+	if data.ValidateMovie(v, movie); !v.IsValid() {
+		return nil, graphqlValidationError(v.Errors)
+	}
+
+	err = app.models.Movies.UpdateMovie(p.Context, movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflic):
+			return nil, errGraphQLEditConflict
+		default:
+			return nil, app.graphqlServerError(p.Context, err)
+		}
+	}
+
+	app.outbox.Notify()
+	return movie, nil
+}
+
+func (app *application) resolveDeleteMovie(p graphql.ResolveParams) (any, error) {
+	id := p.Args["id"].(int)
+
+	err := app.models.Movies.DeleteByID(p.Context, int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, errGraphQLNotFound
+		default:
+			return nil, app.graphqlServerError(p.Context, err)
+		}
+	}
+
+	app.outbox.Notify()
+	return id, nil
+}
+
+func applyMovieInput(movie *data.Movie, input map[string]any) {
+	if title, ok := input["title"].(string); ok {
+		movie.Title = title
+	}
+	if runtime, ok := input["runtime"].(int); ok {
+		movie.Runtime = data.Runtime(runtime)
+	}
+	if year, ok := input["year"].(int); ok {
+		movie.Year = int32(year)
+	}
+	if genres, ok := input["genres"].([]any); ok {
+		movie.Genres = make([]string, 0, len(genres))
+		for _, genre := range genres {
+			if genre, ok := genre.(string); ok {
+				movie.Genres = append(movie.Genres, genre)
+			}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+	greenlightv1 "github.com/Yusufdot101/greenlight/rpc/greenlight/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcPermissions maps each gRPC method to the permission code its REST counterpart requires.
+// Methods that are not listed can be called anonymously.
+var grpcPermissions = map[string]string{
+	greenlightv1.MovieService_CreateMovie_FullMethodName: "movies:write",
+	greenlightv1.MovieService_GetMovie_FullMethodName:    "movies:read",
+	greenlightv1.MovieService_UpdateMovie_FullMethodName: "movies:write",
+	greenlightv1.MovieService_DeleteMovie_FullMethodName: "movies:write",
+	greenlightv1.MovieService_ListMovies_FullMethodName:  "movies:read",
+}
+
+func (app *application) newGRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ConnectionTimeout(10*time.Second),
+		grpc.ChainUnaryInterceptor(
+			app.grpcTrace,
+			app.grpcRecoverPanic,
+			app.grpcRateLimiter,
+			app.grpcAuthenticate,
+			app.grpcRequirePermission,
+		),
+	)
+
+	greenlightv1.RegisterMovieServiceServer(srv, &movieServer{app: app})
+	greenlightv1.RegisterTokenServiceServer(srv, &tokenServer{app: app})
+
+	return srv
+}
+
+func (app *application) grpcRecoverPanic(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (res any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = app.grpcServerError(ctx, info.FullMethod, fmt.Errorf("%s", recovered))
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (app *application) grpcRateLimiter(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	if !app.config.limiter.enabled {
+		return handler(ctx, req)
+	}
+
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	if !app.limiter.allow(ip) {
+		app.instruments.limiterRejections.With("grpc").Inc()
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return handler(ctx, req)
+}
+
+func (app *application) grpcAuthenticate(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	authorization := md.Get("authorization")
+	if len(authorization) == 0 {
+		ctx = context.WithValue(ctx, userContextKey, data.AnonymousUser)
+		return handler(ctx, req)
+	}
+
+	headParts := strings.Split(authorization[0], " ")
+	if len(authorization) != 1 || len(headParts) != 2 || headParts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "token invalid or missing")
+	}
+
+	token := headParts[1]
+	v := validator.NewValidator()
+
+	if data.ValidateTokenPlaintext(v, token); !v.IsValid() {
+		return nil, status.Error(codes.Unauthenticated, "token invalid or missing")
+	}
+
+	user, err := app.models.Users.GetUserForToken(ctx, data.ScopeAuthentication, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, status.Error(codes.Unauthenticated, "token invalid or missing")
+		default:
+			return nil, app.grpcServerError(ctx, info.FullMethod, err)
+		}
+	}
+
+	ctx = context.WithValue(ctx, userContextKey, user)
+	return handler(ctx, req)
+}
+
+func (app *application) grpcRequirePermission(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	permission, ok := grpcPermissions[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	user := ctx.Value(userContextKey).(*data.User)
+	if user.IsAnonymous() {
+		return nil, status.Error(
+			codes.Unauthenticated, "you must be authenticated to access this resource",
+		)
+	}
+	if !user.Activated {
+		return nil, status.Error(
+			codes.PermissionDenied, "your user account must be activated to access this resource",
+		)
+	}
+
+	permissions, err := app.models.Permissions.GellAllForUser(ctx, user.ID)
+	if err != nil {
+		return nil, app.grpcServerError(ctx, info.FullMethod, err)
+	}
+	if !permissions.Include(permission) {
+		return nil, status.Error(
+			codes.PermissionDenied,
+			"your user account doesn't have the necessary permissions to access this resource",
+		)
+	}
+
+	return handler(ctx, req)
+}
+
+func (app *application) grpcServerError(ctx context.Context, method string, err error) error {
+	app.logError(ctx, err, map[string]string{"method": method})
+
+	return status.Error(
+		codes.Internal, "the server encountered and error and could not resolve your request",
+	)
+}
+
+func grpcValidationError(errs map[string]string) error {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range fields {
+		badRequest.FieldViolations = append(
+			badRequest.FieldViolations,
+			&errdetails.BadRequest_FieldViolation{Field: field, Description: errs[field]},
+		)
+	}
+
+	st, err := status.New(codes.InvalidArgument, "failed validation").WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "failed validation")
+	}
+
+	return st.Err()
+}
+
+func movieToProto(movie *data.Movie) *greenlightv1.Movie {
+	return &greenlightv1.Movie{
+		Id:        movie.ID,
+		Title:     movie.Title,
+		Runtime:   int32(movie.Runtime),
+		Year:      movie.Year,
+		Genres:    movie.Genres,
+		Version:   movie.Version,
+		CreatedAt: timestamppb.New(movie.CreatedAt),
+	}
+}
+
+type movieServer struct {
+	greenlightv1.UnimplementedMovieServiceServer
+	app *application
+}
+
+func (s *movieServer) CreateMovie(
+	ctx context.Context, req *greenlightv1.CreateMovieRequest,
+) (*greenlightv1.Movie, error) {
+	movie := &data.Movie{
+		Title:   req.GetTitle(),
+		Runtime: data.Runtime(req.GetRuntime()),
+		Year:    req.GetYear(),
+		Genres:  req.GetGenres(),
+	}
+
+	v := validator.NewValidator()
+	if data.ValidateMovie(v, movie); !v.IsValid() {
+		return nil, grpcValidationError(v.Errors)
+	}
+
+	err := s.app.models.Movies.InsertMovie(ctx, movie)
+	if err != nil {
+		return nil, s.app.grpcServerError(
+			ctx, greenlightv1.MovieService_CreateMovie_FullMethodName, err,
+		)
+	}
+
+	s.app.outbox.Notify()
+	return movieToProto(movie), nil
+}
+
+func (s *movieServer) GetMovie(
+	ctx context.Context, req *greenlightv1.GetMovieRequest,
+) (*greenlightv1.Movie, error) {
+	movie, err := s.app.models.Movies.GetByID(ctx, req.GetId())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, status.Error(codes.NotFound, "the movie could not be found")
+		default:
+			return nil, s.app.grpcServerError(
+				ctx, greenlightv1.MovieService_GetMovie_FullMethodName, err,
+			)
+		}
+	}
+
+	return movieToProto(movie), nil
+}
+
+func (s *movieServer) UpdateMovie(
+	ctx context.Context, req *greenlightv1.UpdateMovieRequest,
+) (*greenlightv1.Movie, error) {
+	method := greenlightv1.MovieService_UpdateMovie_FullMethodName
+
+	movie, err := s.app.models.Movies.GetByID(ctx, req.GetId())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, status.Error(codes.NotFound, "the movie could not be found")
+		default:
+			return nil, s.app.grpcServerError(ctx, method, err)
+		}
+	}
+
+	if req.Version != nil && req.GetVersion() != movie.Version {
+		return nil, status.Error(
+			codes.Aborted, "an error occcured and your edit did not go through, please try again",
+		)
+	}
+
+	if req.Title != nil {
+		movie.Title = req.GetTitle()
+	}
+	if req.Runtime != nil {
+		movie.Runtime = data.Runtime(req.GetRuntime())
+	}
+	if req.Year != nil {
+		movie.Year = req.GetYear()
+	}
+	if req.Genres != nil {
+		movie.Genres = req.GetGenres().GetValues()
+	}
+
+	v := validator.NewValidator()
+	if data.ValidateMovie(v, movie); !v.IsValid() {
+		return nil, grpcValidationError(v.Errors)
+	}
+
+	err = s.app.models.Movies.UpdateMovie(ctx, movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflic):
+			return nil, status.Error(
+				codes.Aborted, "an error occcured and your edit did not go through, please try again",
+			)
+		default:
+			return nil, s.app.grpcServerError(ctx, method, err)
+		}
+	}
+
+	s.app.outbox.Notify()
+	return movieToProto(movie), nil
+}
+
+func (s *movieServer) DeleteMovie(
+	ctx context.Context, req *greenlightv1.DeleteMovieRequest,
+) (*greenlightv1.DeleteMovieResponse, error) {
+	err := s.app.models.Movies.DeleteByID(ctx, req.GetId())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, status.Error(codes.NotFound, "the movie could not be found")
+		default:
+			return nil, s.app.grpcServerError(
+				ctx, greenlightv1.MovieService_DeleteMovie_FullMethodName, err,
+			)
+		}
+	}
+
+	s.app.outbox.Notify()
+	return &greenlightv1.DeleteMovieResponse{}, nil
+}
+
+func (s *movieServer) ListMovies(
+	ctx context.Context, req *greenlightv1.ListMoviesRequest,
+) (*greenlightv1.ListMoviesResponse, error) {
+	q := data.MovieQuery{
+		Title:   req.GetTitle(),
+		Genres:  req.GetGenres(),
+		YearMin: int(req.GetYearMin()),
+		YearMax: int(req.GetYearMax()),
+	}
+
+	filter := data.Filter{
+		Page:         int(req.GetPage()),
+		PageSize:     int(req.GetPageSize()),
+		Sort:         req.GetSort(),
+		SafeSortList: movieSafeSortList,
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 100
+	}
+	if filter.Sort == "" {
+		filter.Sort = "id"
+	}
+
+	v := validator.NewValidator()
+	data.ValidateMovieQuery(v, &q)
+	if data.ValidateFilters(v, &filter); !v.IsValid() {
+		return nil, grpcValidationError(v.Errors)
+	}
+
+	movies, metadata, err := s.app.models.Movies.ListMovies(ctx, q, filter)
+	if err != nil {
+		return nil, s.app.grpcServerError(
+			ctx, greenlightv1.MovieService_ListMovies_FullMethodName, err,
+		)
+	}
+
+	res := &greenlightv1.ListMoviesResponse{
+		Movies: make([]*greenlightv1.Movie, len(movies)),
+		Metadata: &greenlightv1.Metadata{
+			CurrentPage:  int32(metadata.CurrentPage),
+			PageSize:     int32(metadata.PageSize),
+			TotalRecords: int32(metadata.TotalRecords),
+			LastPage:     int32(metadata.LastPage),
+		},
+	}
+	for i, movie := range movies {
+		res.Movies[i] = movieToProto(movie)
+	}
+
+	return res, nil
+}
+
+type tokenServer struct {
+	greenlightv1.UnimplementedTokenServiceServer
+	app *application
+}
+
+func (s *tokenServer) CreateAuthenticationToken(
+	ctx context.Context, req *greenlightv1.CreateAuthenticationTokenRequest,
+) (*greenlightv1.AuthenticationToken, error) {
+	method := greenlightv1.TokenService_CreateAuthenticationToken_FullMethodName
+
+	v := validator.NewValidator()
+	data.ValidateEmail(v, req.GetEmail())
+	v.CheckAdd(req.GetPassword() != "", "password", "must be provided")
+	if !v.IsValid() {
+		return nil, grpcValidationError(v.Errors)
+	}
+
+	user, err := s.app.models.Users.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			return nil, status.Error(codes.Unauthenticated, "invaild credentials")
+		default:
+			return nil, s.app.grpcServerError(ctx, method, err)
+		}
+	}
+
+	matches, err := user.Password.Matches(req.GetPassword())
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, method, err)
+	}
+
+	if !matches {
+		return nil, status.Error(codes.Unauthenticated, "invaild credentials")
+	}
+
+	token, err := s.app.models.Tokens.NewToken(
+		ctx, user.ID, 24*time.Hour, data.ScopeAuthentication,
+	)
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, method, err)
+	}
+
+	return &greenlightv1.AuthenticationToken{
+		Token:  token.Plaintext,
+		Expiry: timestamppb.New(token.Expiry),
+	}, nil
+}
@@ -12,7 +12,7 @@ func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	err := app.writeJSON(w, http.StatusOK, env)
+	err := app.writeResponse(w, r, http.StatusOK, env)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
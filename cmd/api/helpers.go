@@ -1,43 +1,152 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/msgpack"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
 	"github.com/Yusufdot101/greenlight/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
 
 type envelope map[string]any
 
-func (app *application) writeJSON(w http.ResponseWriter, statusCode int, message envelope) error {
-	JSON, err := json.MarshalIndent(message, "", "\t")
+// writeResponse encodes message in the representation the client's Accept header prefers,
+// skipping ones such as text/csv that cannot represent this particular message. When none can,
+// it sends a 406 response instead.
+func (app *application) writeResponse(
+	w http.ResponseWriter, r *http.Request, statusCode int, message envelope,
+) error {
+	contentType, body, err := encodeResponse(r, message)
+	if errors.Is(err, errNotEncodable) {
+		app.notAcceptableResponse(w, r)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	JSON = append(JSON, '\n')
-
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(statusCode)
-	_, err = w.Write(JSON)
+
+	_, err = w.Write(body)
+	return err
+}
+
+// writeMovieResponse writes message like writeResponse, along with an ETag for movie as it is
+// represented in this response. A GET whose If-None-Match lists that ETag is sent a 304 instead.
+func (app *application) writeMovieResponse(
+	w http.ResponseWriter, r *http.Request, statusCode int, movie *data.Movie, fields []string,
+	message envelope,
+) error {
+	contentType, body, err := encodeResponse(r, message)
+	if errors.Is(err, errNotEncodable) {
+		app.notAcceptableResponse(w, r)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	etag := movieETag(movie, contentType, fields, r.URL.Query().Has("pretty"))
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if r.Method == http.MethodGet && ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	_, err = w.Write(body)
+	return err
+}
+
+// encodeResponse encodes message as writeResponse does and returns it along with its content
+// type, or errNotEncodable if no acceptable representation can hold it.
+func encodeResponse(r *http.Request, message envelope) (string, []byte, error) {
+	pretty := r.URL.Query().Has("pretty")
+
+	var buf bytes.Buffer
+	for _, encoder := range acceptableEncoders(r.Header.Get("Accept")) {
+		buf.Reset()
+
+		err := encoder.encode(&buf, message, pretty)
+		if errors.Is(err, errNotEncodable) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		return encoder.contentType, buf.Bytes(), nil
+	}
+
+	return "", nil, errNotEncodable
 }
 
+// readJSON decodes the request body into dst. JSON, including types with a +json suffix such as
+// application/merge-patch+json, is decoded directly, while MessagePack is first translated to
+// JSON so both go through the same checks. Bodies of any other type return
+// errUnsupportedMediaType.
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	const maxBytes = 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 
-	decoder := json.NewDecoder(r.Body)
+	var body io.Reader = r.Body
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "", mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+	case mediaType == "application/msgpack", mediaType == "application/x-msgpack",
+		mediaType == "application/vnd.msgpack":
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			if err.Error() == "http: request body too large" {
+				return fmt.Errorf("body size cannot exceed %d bytes", maxBytes)
+			}
+			return err
+		}
+		if len(b) == 0 {
+			return fmt.Errorf("body cannot be empty")
+		}
+
+		value, err := msgpack.Unmarshal(b)
+		if err != nil {
+			return fmt.Errorf("body contains badly formed MessagePack: %w", err)
+		}
+
+		JSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("body contains a value that cannot be represented as JSON: %w", err)
+		}
+		body = bytes.NewReader(JSON)
+	default:
+		return errUnsupportedMediaType
+	}
+
+	decoder := json.NewDecoder(body)
 	decoder.DisallowUnknownFields()
 
 	err := decoder.Decode(dst)
@@ -120,6 +229,23 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return id
 }
 
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return &t
+		}
+	}
+
+	v.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return nil
+}
+
 func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
 	s := qs.Get(key)
 	if s == "" {
@@ -129,15 +255,265 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(s, ",")
 }
 
-func (app *application) background(fn func()) {
+func (app *application) readAcceptLanguage(r *http.Request) []string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality > 0 {
+			preferences = append(preferences, preference{tag: tag, quality: quality})
+		}
+	}
+
+	slices.SortStableFunc(preferences, func(a, b preference) int {
+		return cmp.Compare(b.quality, a.quality)
+	})
+
+	tags := make([]string, len(preferences))
+	for i, preference := range preferences {
+		tags[i] = preference.tag
+	}
+
+	return tags
+}
+
+// negotiate picks the offered media type the Accept header prefers. Ties go to the earlier
+// offer, a missing header accepts the first one, and "" is returned when none are acceptable.
+func negotiate(accept string, offers ...string) string {
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality := acceptQuality(accept, offer)
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+
+	return best
+}
+
+// acceptQuality returns the quality the Accept header gives mediaType, taken from the most
+// specific range that matches it.
+func acceptQuality(accept, mediaType string) float64 {
+	if strings.TrimSpace(accept) == "" {
+		return 1
+	}
+
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+		var rangeSpecificity int
+		switch mediaRange {
+		case mediaType:
+			rangeSpecificity = 2
+		case typ + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+		if rangeSpecificity < specificity {
+			continue
+		}
+
+		rangeQuality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if q, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				parsed, err := strconv.ParseFloat(q, 64)
+				if err == nil {
+					rangeQuality = parsed
+				}
+			}
+		}
+
+		quality, specificity = rangeQuality, rangeSpecificity
+	}
+
+	return quality
+}
+
+func (app *application) pickFields(value any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return value, nil
+	}
+
+	JSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(JSON))
+	decoder.UseNumber()
+
+	var decoded any
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	pick := func(object map[string]any) map[string]any {
+		picked := make(map[string]any, len(fields))
+		for _, field := range fields {
+			if fieldValue, exists := object[field]; exists {
+				picked[field] = fieldValue
+			}
+		}
+		return picked
+	}
+
+	switch decoded := decoded.(type) {
+	case map[string]any:
+		return pick(decoded), nil
+	case []any:
+		for i, element := range decoded {
+			if object, ok := element.(map[string]any); ok {
+				decoded[i] = pick(object)
+			}
+		}
+		return decoded, nil
+	default:
+		return decoded, nil
+	}
+}
+
+// background runs fn in its own goroutine, in a span that continues the trace of ctx. ctx keeps
+// the request's log properties, such as its ID, but is not cancelled along with the request,
+// since that usually finishes first.
+func (app *application) background(ctx context.Context, name string, fn func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+
 	app.wg.Add(1)
 	go func() {
 		defer app.wg.Done()
+
+		ctx, span := tracing.Start(ctx, name, tracing.SpanKindInternal)
+		defer span.End()
+
 		defer func() {
-			if err := recover(); err != nil {
-				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			if recovered := recover(); recovered != nil {
+				err := fmt.Errorf("%s", recovered)
+				span.RecordError(err)
+				app.logger.WithContext(ctx).PrintError(err, nil)
 			}
 		}()
-		fn()
+		fn(ctx)
 	}()
 }
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		isAlphanumeric := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		if !isAlphanumeric && !strings.ContainsRune("-_.:", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// movieETag returns a strong ETag for the representation of movie encoded as contentType and
+// limited to fields, so that representations that differ by even a byte never share one. All
+// of them start with the movie's id and version, which is what If-Match is checked against.
+func movieETag(movie *data.Movie, contentType string, fields []string, pretty bool) string {
+	fields = slices.Clone(fields)
+	slices.Sort(fields)
+	fields = slices.Compact(fields)
+
+	variant := sha256.Sum256(
+		fmt.Appendf(nil, "%s;%s;%t", contentType, strings.Join(fields, ","), pretty),
+	)
+	return fmt.Sprintf(`"%d-%d-%x"`, movie.ID, movie.Version, variant[:4])
+}
+
+// etagMatches reports whether etag is listed in an If-None-Match header value, using the weak
+// comparison, which ignores the W/ prefix.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagMatchesMovie reports whether an If-Match header value lists an ETag of any
+// representation of the current version of movie. Writes replace the movie as a whole, so it
+// does not matter which representation the client based its request on. Weak ETags never match.
+func etagMatchesMovie(header string, movie *data.Movie) bool {
+	version := fmt.Sprintf("%d-%d", movie.ID, movie.Version)
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		tag, ok := strings.CutPrefix(candidate, `"`+version)
+		if ok && (tag == `"` || strings.HasPrefix(tag, "-") && strings.HasSuffix(tag, `"`)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition on writes to movie. It sends the error
+// response itself and returns false when the request should not go ahead, and reports through
+// conditional whether the client sent the header at all.
+func (app *application) checkIfMatch(
+	w http.ResponseWriter, r *http.Request, movie *data.Movie,
+) (ok, conditional bool) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if app.config.requireIfMatch {
+			app.preconditionRequiredResponse(w, r)
+			return false, false
+		}
+		return true, false
+	}
+
+	if !etagMatchesMovie(ifMatch, movie) {
+		app.preconditionFailedResponse(w, r)
+		return false, true
+	}
+
+	return true, true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/felixge/httpsnoop"
+)
+
+const (
+	idempotencyKeyMaxLength = 255
+	// idempotencyLockTimeout is how long a key stays claimed by a request that never finished,
+	// for example because the server crashed while handling it, before a retry may take it over.
+	idempotencyLockTimeout = time.Minute
+)
+
+// idempotency replays the stored response for POST requests that repeat an Idempotency-Key the
+// same user has already sent, rather than running them again. Keys are tied to a fingerprint of
+// the request, so a key reused for a different request is rejected, and a repeat that arrives
+// while the first request is still being handled is told to retry later.
+//
+// Anonymous requests are passed through untouched. Their keys would all share one key space,
+// so one client could be refused, or sent the response to, a request that another client made.
+func (app *application) idempotency(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		user := app.contextGetUser(r)
+		if r.Method != http.MethodPost || key == "" || user.IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			app.failedValidationResponse(w, r, map[string]string{
+				"Idempotency-Key": fmt.Sprintf(
+					"cannot be more than %d characters", idempotencyKeyMaxLength,
+				),
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, importMaxBytes))
+		if err != nil {
+			app.badRequestResponse(
+				w, r, fmt.Errorf("body size cannot exceed %d bytes", importMaxBytes),
+			)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+
+		claimed, existing, err := app.models.Idempotency.Claim(
+			r.Context(), user.ID, key, fingerprint,
+			app.config.idempotency.ttl, idempotencyLockTimeout,
+		)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !claimed {
+			app.replayIdempotent(w, r, existing, fingerprint)
+			return
+		}
+
+		var status int
+		var response bytes.Buffer
+		recorder := httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					if status == 0 {
+						status = code
+					}
+					next(code)
+				}
+			},
+			Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+				return func(b []byte) (int, error) {
+					if status == 0 {
+						status = http.StatusOK
+					}
+					response.Write(b)
+					return next(b)
+				}
+			},
+		})
+
+		// the key is released unless a response worth replaying was sent, including when the
+		// handler panics, so that the client is free to try again
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+
+			err := app.models.Idempotency.Release(
+				context.WithoutCancel(r.Context()), user.ID, key,
+			)
+			if err != nil {
+				app.logError(r.Context(), err, map[string]string{"idempotency_key": key})
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		if status == 0 || status >= http.StatusInternalServerError {
+			return
+		}
+
+		headers := w.Header().Clone()
+		headers.Del("X-Request-ID")
+
+		err = app.models.Idempotency.Complete(
+			context.WithoutCancel(r.Context()), user.ID, key, status, headers, response.Bytes(),
+		)
+		if err != nil {
+			app.logError(r.Context(), err, map[string]string{"idempotency_key": key})
+			return
+		}
+		completed = true
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// replayIdempotent answers a request whose Idempotency-Key was already claimed by existing.
+func (app *application) replayIdempotent(
+	w http.ResponseWriter, r *http.Request, existing *data.IdempotencyKey, fingerprint string,
+) {
+	switch {
+	case existing.Fingerprint != fingerprint:
+		app.idempotencyKeyReusedResponse(w, r)
+	case existing.Status == data.IdempotencyProcessing:
+		app.idempotencyKeyInProgressResponse(w, r)
+	default:
+		for name, values := range existing.ResponseHeaders {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.ResponseStatus)
+		w.Write(existing.ResponseBody)
+	}
+}
+
+// requestFingerprint identifies what a request asks for, so that a retry can be told apart from
+// a different request that happens to reuse the same Idempotency-Key.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), strconv.Itoa(len(body)),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// pruneIdempotencyKeys deletes expired keys every hour. Expired keys are already ignored when
+// they are sent again, so this only keeps the table from growing.
+func (app *application) pruneIdempotencyKeys() {
+	go func() {
+		for {
+			time.Sleep(time.Hour)
+
+			_, err := app.models.Idempotency.DeleteExpired(context.Background())
+			if err != nil {
+				app.logError(context.Background(), err, map[string]string{
+					"stage": "pruning idempotency keys",
+				})
+			}
+		}
+	}()
+}
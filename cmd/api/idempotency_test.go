@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+)
+
+func TestRequestFingerprint(t *testing.T) {
+	type request struct {
+		method, target, contentType, body string
+	}
+
+	fingerprint := func(req request) string {
+		r := httptest.NewRequest(req.method, req.target, strings.NewReader(req.body))
+		r.Header.Set("Content-Type", req.contentType)
+		return requestFingerprint(r, []byte(req.body))
+	}
+
+	original := request{http.MethodPost, "/v1/movies", "application/json", `{"title":"Moana"}`}
+
+	tests := []struct {
+		name     string
+		change   func(req *request)
+		wantSame bool
+	}{
+		{"retry", func(req *request) {}, true},
+		{"different body", func(req *request) { req.body = `{"title":"Coco"}` }, false},
+		{"different path", func(req *request) { req.target = "/v1/movies/batch" }, false},
+		{"different query", func(req *request) { req.target = "/v1/movies?pretty" }, false},
+		{"different type", func(req *request) { req.contentType = "application/msgpack" }, false},
+		{"different method", func(req *request) { req.method = http.MethodPut }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := original
+			tt.change(&req)
+
+			same := fingerprint(req) == fingerprint(original)
+			if same != tt.wantSame {
+				t.Errorf("got same fingerprint %t; want %t", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestReplayIdempotent(t *testing.T) {
+	completed := &data.IdempotencyKey{
+		Fingerprint:    "f1",
+		Status:         data.IdempotencyCompleted,
+		ResponseStatus: http.StatusCreated,
+		ResponseHeaders: http.Header{
+			"Content-Type": {"application/json"},
+			"Location":     {"/v1/movies/7"},
+		},
+		ResponseBody: []byte(`{"movie":{"id":7}}` + "\n"),
+	}
+	processing := &data.IdempotencyKey{Fingerprint: "f1", Status: data.IdempotencyProcessing}
+
+	tests := []struct {
+		name        string
+		existing    *data.IdempotencyKey
+		fingerprint string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"replay", completed, "f1", http.StatusCreated, ""},
+		{
+			"completed with another fingerprint",
+			completed,
+			"f2",
+			http.StatusUnprocessableEntity,
+			"idempotency_key_reused",
+		},
+		{"still processing", processing, "f1", http.StatusConflict, "idempotency_key_in_progress"},
+		{
+			"processing with another fingerprint",
+			processing,
+			"f2",
+			http.StatusUnprocessableEntity,
+			"idempotency_key_reused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/movies", nil)
+			rr := httptest.NewRecorder()
+
+			app.replayIdempotent(rr, r, tt.existing, tt.fingerprint)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			if tt.wantCode == "" {
+				if got := rr.Header().Get("Idempotent-Replayed"); got != "true" {
+					t.Errorf("got Idempotent-Replayed %q; want %q", got, "true")
+				}
+				if got := rr.Header().Get("Location"); got != "/v1/movies/7" {
+					t.Errorf("got Location %q; want %q", got, "/v1/movies/7")
+				}
+				if got := rr.Body.String(); got != string(tt.existing.ResponseBody) {
+					t.Errorf("got body %q; want %q", got, tt.existing.ResponseBody)
+				}
+				return
+			}
+
+			if rr.Header().Get("Idempotent-Replayed") != "" {
+				t.Error("an error response was marked as replayed")
+			}
+
+			var problem struct {
+				Code string `json:"code"`
+			}
+			err := json.Unmarshal(rr.Body.Bytes(), &problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("got code %q; want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	user := &data.User{ID: 1}
+	anonymous := data.AnonymousUser
+	tooLong := strings.Repeat("k", idempotencyKeyMaxLength+1)
+
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		user       *data.User
+		wantNext   bool
+		wantStatus int
+	}{
+		{"post without key", http.MethodPost, "", user, true, http.StatusOK},
+		{"get with key", http.MethodGet, "abc", user, true, http.StatusOK},
+		{"anonymous post with key", http.MethodPost, "abc", anonymous, true, http.StatusOK},
+		{"key too long", http.MethodPost, tooLong, user, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+
+			r := httptest.NewRequest(tt.method, "/v1/movies", strings.NewReader(`{}`))
+			if tt.key != "" {
+				r.Header.Set("Idempotency-Key", tt.key)
+			}
+			r = app.contextSetUser(r, tt.user)
+			rr := httptest.NewRecorder()
+
+			app.idempotency(next).ServeHTTP(rr, r)
+
+			if called != tt.wantNext {
+				t.Errorf("got next called %t; want %t", called, tt.wantNext)
+			}
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
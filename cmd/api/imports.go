@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+)
+
+const importMaxBytes = 16 << 20
+
+type importRow struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+	movie  *data.Movie
+}
+
+type importReport struct {
+	Mode    string       `json:"mode"`
+	DryRun  bool         `json:"dry_run"`
+	Total   int          `json:"total"`
+	Invalid int          `json:"invalid"`
+	Errors  []*importRow `json:"errors"`
+	Job     *data.Job    `json:"job,omitempty"`
+}
+
+func (app *application) importMovies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	mode := app.readString(qs, "mode", "atomic")
+	dryRun := app.readString(qs, "dry_run", "false")
+
+	v.CheckAdd(
+		validator.ValueInList(mode, "atomic", "best_effort"), "mode",
+		"must be either atomic or best_effort",
+	)
+	v.CheckAdd(validator.ValueInList(dryRun, "true", "false"), "dry_run", "must be a boolean")
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var parse func(io.Reader) ([]*importRow, error)
+	switch mediaType {
+	case "text/csv":
+		parse = parseCSVImport
+	case "application/x-ndjson", "application/ndjson":
+		parse = parseNDJSONImport
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	rows, err := parse(http.MaxBytesReader(w, r.Body, importMaxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			app.badRequestResponse(w, r, fmt.Errorf("body size cannot exceed %d bytes", importMaxBytes))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	report := &importReport{
+		Mode:   mode,
+		DryRun: dryRun == "true",
+		Total:  len(rows),
+		Errors: []*importRow{},
+	}
+
+	var valid []*importRow
+	for _, row := range rows {
+		if row.Errors == nil {
+			v := validator.NewValidator()
+			data.ValidateMovie(v, row.movie)
+			if !v.IsValid() {
+				row.Errors = v.Errors
+			}
+		}
+
+		if row.Errors != nil {
+			report.Errors = append(report.Errors, row)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	report.Invalid = len(report.Errors)
+
+	if report.DryRun || (mode == "atomic" && report.Invalid > 0) || len(valid) == 0 {
+		status := http.StatusOK
+		if !report.DryRun && report.Invalid > 0 {
+			status = http.StatusBadRequest
+		}
+
+		err = app.writeResponse(w, r, status, envelope{"import": report})
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	payload := movieImportPayload{
+		Atomic: mode == "atomic",
+		Rows:   make([]movieImportRecord, len(valid)),
+	}
+	for i, row := range valid {
+		payload.Rows[i] = movieImportRecord{Row: row.Row, Movie: row.movie}
+	}
+
+	report.Job, err = app.enqueue(r.Context(), jobMovieImport, payload, 0)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusAccepted, envelope{"import": report})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Jobs.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if job.Kind != jobMovieImport {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeResponse(
+		w, r, http.StatusOK, envelope{
+			"import": envelope{
+				"id":         job.ID,
+				"status":     job.Status,
+				"attempts":   job.Attempts,
+				"created_at": job.CreatedAt,
+				"result":     job.Result,
+			},
+		},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func parseCSVImport(body io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("body cannot be empty")
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !validator.ValueInList(name, "title", "runtime", "year", "genres") {
+			return nil, fmt.Errorf("header contains unknown column: %s", name)
+		}
+		columns[name] = i
+	}
+
+	var rows []*importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) == data.MaxImportRows {
+			return nil, fmt.Errorf("body cannot contain more than %d rows", data.MaxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &importRow{Row: line, movie: &data.Movie{}}
+		v := validator.NewValidator()
+
+		field := func(name string) string {
+			i, exists := columns[name]
+			if !exists || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row.movie.Title = field("title")
+
+		if runtime := field("runtime"); runtime != "" {
+			minutes, err := strconv.ParseInt(strings.TrimSuffix(runtime, " mins"), 10, 32)
+			v.CheckAdd(err == nil, "runtime", "must be a whole number of minutes")
+			row.movie.Runtime = data.Runtime(minutes)
+		}
+
+		if year := field("year"); year != "" {
+			parsed, err := strconv.ParseInt(year, 10, 32)
+			v.CheckAdd(err == nil, "year", "must be integer")
+			row.movie.Year = int32(parsed)
+		}
+
+		if genres := field("genres"); genres != "" {
+			row.movie.Genres = strings.Split(genres, "|")
+		}
+
+		if !v.IsValid() {
+			row.Errors = v.Errors
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseNDJSONImport(body io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1_048_576)
+
+	var rows []*importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		if len(rows) == data.MaxImportRows {
+			return nil, fmt.Errorf("body cannot contain more than %d rows", data.MaxImportRows)
+		}
+
+		var input struct {
+			Title   string       `json:"title"`
+			Runtime data.Runtime `json:"runtime"`
+			Year    int32        `json:"year"`
+			Genres  []string     `json:"genres"`
+		}
+
+		row := &importRow{Row: line}
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&input)
+		if err != nil {
+			row.Errors = map[string]string{"json": err.Error()}
+		}
+
+		row.movie = &data.Movie{
+			Title:   input.Title,
+			Runtime: input.Runtime,
+			Year:    input.Year,
+			Genres:  input.Genres,
+		}
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("body cannot be empty")
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+)
+
+const (
+	jobUserWelcomeEmail = "user_welcome_email"
+	jobMovieImport      = "movie_import"
+)
+
+func (app *application) registerJobHandlers() {
+	app.jobs.Handle(jobUserWelcomeEmail, app.sendUserWelcomeEmail)
+	app.jobs.Handle(jobMovieImport, app.runMovieImport)
+	app.jobs.Handle(jobWebhookDelivery, app.deliverWebhook)
+}
+
+// enqueue adds a job, linking it to the trace in ctx so its attempts show up in the same trace.
+func (app *application) enqueue(
+	ctx context.Context, kind string, payload any, priority int,
+) (*data.Job, error) {
+	job, err := app.newJob(ctx, kind, payload, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.Jobs.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// newJob builds the job that enqueue adds, for callers that insert it as part of a larger
+// transaction instead.
+func (app *application) newJob(
+	ctx context.Context, kind string, payload any, priority int,
+) (*data.Job, error) {
+	JSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &data.Job{
+		Kind:        kind,
+		Payload:     JSON,
+		Priority:    priority,
+		MaxAttempts: app.config.jobs.maxAttempts,
+	}
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		job.Traceparent = sc.Traceparent()
+	}
+
+	return job, nil
+}
+
+type userWelcomeEmailPayload struct {
+	UserID  int64    `json:"user_id"`
+	Locales []string `json:"locales,omitempty"`
+}
+
+func (app *application) sendUserWelcomeEmail(ctx context.Context, job *data.Job) error {
+	var payload userWelcomeEmailPayload
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.models.Users.GetUserByID(ctx, payload.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user.Activated {
+		return nil
+	}
+
+	// a retry sends a fresh token, so drop the ones sent by earlier attempts that may or may not
+	// have been delivered
+	err = app.models.Tokens.DeleteAllForUser(ctx, user.ID, data.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.NewToken(ctx, user.ID, 3*24*time.Hour, data.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	templateData := map[string]any{
+		"Name":            user.Name,
+		"userID":          user.ID,
+		"activationToken": token.Plaintext,
+	}
+
+	locales := payload.Locales
+	if user.Locale != "" {
+		locales = append([]string{user.Locale}, locales...)
+	}
+
+	return app.mailer.Send(ctx, user.Email, "user_welcome", locales, templateData)
+}
+
+type movieImportPayload struct {
+	Atomic bool                `json:"atomic"`
+	Rows   []movieImportRecord `json:"rows"`
+}
+
+type movieImportRecord struct {
+	Row   int         `json:"row"`
+	Movie *data.Movie `json:"movie"`
+}
+
+type movieImportResult struct {
+	Imported int          `json:"imported"`
+	Failed   int          `json:"failed"`
+	Errors   []*importRow `json:"errors"`
+}
+
+func (app *application) runMovieImport(ctx context.Context, job *data.Job) error {
+	var payload movieImportPayload
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	movies := make([]*data.Movie, len(payload.Rows))
+	for i, row := range payload.Rows {
+		movies[i] = row.Movie
+	}
+
+	failed, err := app.models.Movies.ImportMovies(ctx, movies, payload.Atomic)
+	if err != nil {
+		return err
+	}
+
+	// in atomic mode a single rejected row aborts the import, and the others point at it
+	abortedBy := 0
+	for i, row := range payload.Rows {
+		var rejected *data.MovieRejectedError
+		if errors.As(failed[i], &rejected) {
+			abortedBy = row.Row
+		}
+	}
+
+	result := movieImportResult{
+		Imported: len(movies) - len(failed),
+		Failed:   len(failed),
+		Errors:   []*importRow{},
+	}
+	for i, row := range payload.Rows {
+		err, ok := failed[i]
+		if !ok {
+			continue
+		}
+
+		errs, known := importRowErrors(err, abortedBy)
+		if !known {
+			app.logError(ctx, err, map[string]string{
+				"job_id": strconv.FormatInt(job.ID, 10),
+				"row":    strconv.Itoa(row.Row),
+			})
+		}
+		result.Errors = append(result.Errors, &importRow{Row: row.Row, Errors: errs})
+	}
+
+	job.Result, err = json.Marshal(result)
+	return err
+}
+
+// importRowErrors describes why a row was not imported, by field, and reports whether the cause
+// was known. abortedBy is the row that aborted an atomic import.
+func importRowErrors(err error, abortedBy int) (map[string]string, bool) {
+	var rejected *data.MovieRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return rejected.Errors, true
+	case errors.Is(err, data.ErrImportAborted):
+		return map[string]string{
+			"row": fmt.Sprintf("not attempted because row %d was rejected", abortedBy),
+		}, true
+	default:
+		return map[string]string{"row": "could not be inserted"}, false
+	}
+}
+
+func (app *application) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		Kind   string
+		data.Filter
+	}
+
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	input.Status = app.readString(qs, "status", "")
+	input.Kind = app.readString(qs, "kind", "")
+	input.Page = app.readInt(qs, "page", 1, v)
+	input.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Sort = app.readString(qs, "sort", "-id")
+	input.SafeSortList = []string{
+		"id", "-id",
+		"priority", "-priority",
+		"run_at", "-run_at",
+		"attempts", "-attempts",
+	}
+
+	v.CheckAdd(
+		input.Status == "" || validator.ValueInList(input.Status, data.JobStatuses...), "status",
+		"must be one of pending, running, completed or dead",
+	)
+
+	if data.ValidateFilters(v, &input.Filter); !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	jobs, metadata, err := app.models.Jobs.ListJobs(
+		r.Context(), input.Status, input.Kind, input.Filter,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"metadata": metadata, "jobs": jobs})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Jobs.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"job": job})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) retryJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Jobs.Retry(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrJobNotRetryable):
+			app.jobNotRetryableResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(
+		w, r, http.StatusAccepted, envelope{
+			"message": "job scheduled for retry",
+			"job":     job,
+		},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+)
+
+func TestImportRowErrors(t *testing.T) {
+	rejected := &data.MovieRejectedError{
+		Errors: map[string]string{"runtime": "must be postive integer"},
+		Err:    errors.New(`pq: violates check constraint "movies_runtime_check"`),
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantErrs  map[string]string
+		wantKnown bool
+	}{
+		{"rejected", rejected, rejected.Errors, true},
+		{
+			"aborted",
+			data.ErrImportAborted,
+			map[string]string{"row": "not attempted because row 4 was rejected"},
+			true,
+		},
+		{
+			"unexpected error",
+			errors.New("connection reset"),
+			map[string]string{"row": "could not be inserted"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, known := importRowErrors(tt.err, 4)
+
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("got errors %v; want %v", errs, tt.wantErrs)
+			}
+			if known != tt.wantKnown {
+				t.Errorf("got known %t; want %t", known, tt.wantKnown)
+			}
+		})
+	}
+}
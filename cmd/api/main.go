@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
@@ -14,8 +15,14 @@ import (
 	"time"
 
 	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jobs"
 	"github.com/Yusufdot101/greenlight/internal/jsonlog"
 	"github.com/Yusufdot101/greenlight/internal/mailer"
+	"github.com/Yusufdot101/greenlight/internal/outbox"
+	"github.com/Yusufdot101/greenlight/internal/stream"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+	"github.com/Yusufdot101/greenlight/internal/webhooks"
+	"github.com/graphql-go/graphql"
 	_ "github.com/lib/pq"
 )
 
@@ -26,8 +33,11 @@ var (
 
 type config struct {
 	port int
-	env  string
-	db   struct {
+	grpc struct {
+		port int
+	}
+	env string
+	db  struct {
 		dsn             string
 		maxOpenConns    int
 		maxIdleConns    int
@@ -45,17 +55,59 @@ type config struct {
 		password string
 		sender   string
 	}
+	mailer struct {
+		transport string
+		dir       string
+	}
 	cors struct {
 		trustedOrigins []string
 	}
+	jobs struct {
+		workers      int
+		pollInterval time.Duration
+		lockTimeout  time.Duration
+		timeout      time.Duration
+		maxAttempts  int
+	}
+	graphql struct {
+		maxDepth      int
+		maxComplexity int
+	}
+	outbox struct {
+		sinks        []string
+		file         string
+		url          string
+		pollInterval time.Duration
+		retention    time.Duration
+	}
+	idempotency struct {
+		ttl time.Duration
+	}
+	metrics struct {
+		port int
+	}
+	trace struct {
+		exporter string
+		file     string
+	}
+	requireIfMatch bool
 }
 
 type application struct {
-	config config
-	models *data.Models
-	logger *jsonlog.Logger
-	mailer *mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	models   *data.Models
+	logger   *jsonlog.Logger
+	mailer   *mailer.Mailer
+	jobs     *jobs.Runner
+	outbox   *outbox.Relay
+	stream   *stream.Broker
+	graphql  graphql.Schema
+	webhooks *webhooks.Client
+	limiter  *clientLimiter
+	wg       sync.WaitGroup
+
+	instruments *instruments
+	tracer      *tracing.Tracer
 }
 
 func main() {
@@ -66,6 +118,18 @@ func main() {
 	}
 	flag.IntVar(&cfg.port, "port", mustAtoi(port), "API server port")
 
+	flag.IntVar(&cfg.grpc.port, "grpc-port", 0, "gRPC server port (0, the default, disables it)")
+
+	flag.IntVar(
+		&cfg.metrics.port, "metrics-port", 0,
+		"port to serve /metrics and /debug/vars on instead of the API port (0 uses the API port)",
+	)
+
+	flag.BoolVar(
+		&cfg.requireIfMatch, "require-if-match", false,
+		"reject PATCH, PUT and DELETE requests on a movie that do not send an If-Match header",
+	)
+
 	flag.StringVar(&cfg.env, "env", "development", "environment (development|staging|production)")
 
 	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgreSQL DSN")
@@ -82,15 +146,77 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 5, "rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "enable rate limiter")
 
-	flag.StringVar(&cfg.smtp.host, "smtp-host", "sandbox.smtp.mailtrap.io", "SMTP host")
+	flag.StringVar(&cfg.smtp.host, "smtp-host", os.Getenv("GREENLIGHT_SMTP_HOST"), "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
-	flag.StringVar(&cfg.smtp.username, "smtp-username", "3b009b986e9a42", "SMTP username")
-	flag.StringVar(&cfg.smtp.password, "smtp-password", "5554cb8d083921", "SMTP password")
+	flag.StringVar(
+		&cfg.smtp.username, "smtp-username", os.Getenv("GREENLIGHT_SMTP_USERNAME"), "SMTP username",
+	)
+	flag.StringVar(
+		&cfg.smtp.password, "smtp-password", os.Getenv("GREENLIGHT_SMTP_PASSWORD"), "SMTP password",
+	)
 	flag.StringVar(
 		&cfg.smtp.sender, "smtp-sender", "Greenlight <noreply@greenlight.ym.net>",
 		"SMTP sender",
 	)
 
+	flag.StringVar(
+		&cfg.mailer.transport, "mailer-transport", "",
+		"mail transport (smtp|file|log|memory), defaults to log in development and smtp otherwise",
+	)
+	flag.StringVar(&cfg.mailer.dir, "mailer-dir", "./tmp/mail", "maildir used by the file transport")
+
+	flag.IntVar(&cfg.jobs.workers, "jobs-workers", 4, "number of background job workers")
+	flag.DurationVar(
+		&cfg.jobs.pollInterval, "jobs-poll-interval", time.Second,
+		"how often idle job workers poll for new jobs",
+	)
+	flag.DurationVar(
+		&cfg.jobs.lockTimeout, "jobs-lock-timeout", 5*time.Minute,
+		"how long a running job may go without finishing before it is retried",
+	)
+	flag.DurationVar(
+		&cfg.jobs.timeout, "jobs-timeout", time.Minute,
+		"how long a job attempt may run before it is cancelled, less than -jobs-lock-timeout",
+	)
+	flag.IntVar(&cfg.jobs.maxAttempts, "jobs-max-attempts", 5, "job attempts before dead-lettering")
+
+	flag.IntVar(&cfg.graphql.maxDepth, "graphql-max-depth", 8, "maximum GraphQL query depth")
+	flag.IntVar(
+		&cfg.graphql.maxComplexity, "graphql-max-complexity", 2000,
+		"maximum GraphQL query complexity, with paginated fields costing once per item",
+	)
+
+	flag.DurationVar(
+		&cfg.idempotency.ttl, "idempotency-ttl", 24*time.Hour,
+		"how long responses to requests with an Idempotency-Key are kept for replay",
+	)
+
+	flag.StringVar(
+		&cfg.trace.exporter, "trace-exporter", "none",
+		"where finished spans are written as OTLP/JSON (none|stdout|file)",
+	)
+	flag.StringVar(
+		&cfg.trace.file, "trace-file", "./tmp/traces.ndjson", "file used by the file exporter",
+	)
+
+	flag.Func(
+		"outbox-sinks", "outbox sinks to publish events to (space separated: stdout|file|http)",
+		func(val string) error {
+			cfg.outbox.sinks = strings.Fields(val)
+			return nil
+		},
+	)
+	flag.StringVar(&cfg.outbox.file, "outbox-file", "./tmp/events.ndjson", "file used by the file sink")
+	flag.StringVar(&cfg.outbox.url, "outbox-url", "", "endpoint events are POSTed to by the http sink")
+	flag.DurationVar(
+		&cfg.outbox.pollInterval, "outbox-poll-interval", time.Second,
+		"how often the outbox relay polls for unpublished events",
+	)
+	flag.DurationVar(
+		&cfg.outbox.retention, "outbox-retention", 7*24*time.Hour,
+		"how long published events are kept in the outbox (0 keeps them forever)",
+	)
+
 	flag.Func("cors-trusted-origins", "Trusted CORS origin (space separated)",
 		func(val string) error {
 			cfg.cors.trustedOrigins = strings.Fields(val)
@@ -110,6 +236,11 @@ func main() {
 
 	logger := jsonlog.NewLogger(os.Stdout, jsonlog.Level(*minLevel))
 
+	if cfg.jobs.timeout >= cfg.jobs.lockTimeout {
+		logger.PrintFatal(errors.New("-jobs-timeout must be less than -jobs-lock-timeout"), nil)
+		return
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -117,6 +248,36 @@ func main() {
 	}
 	logger.PrintIfo("connection to the db established", nil)
 
+	instruments := newInstruments(db)
+
+	exporter, err := newTraceExporter(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+		return
+	}
+
+	tracer := tracing.NewTracer(exporter, tracing.Config{
+		BatchSize:     512,
+		FlushInterval: 5 * time.Second,
+		OnError: func(err error) {
+			logger.PrintError(err, map[string]string{"stage": "exporting spans"})
+		},
+	})
+
+	transport, err := newMailTransport(cfg, logger)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+		return
+	}
+
+	emailer, err := mailer.NewMailer(
+		instrumentedTransport{Transport: transport, mail: instruments.mail}, cfg.smtp.sender,
+	)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+		return
+	}
+
 	expvar.NewString("version").Set(version)
 	expvar.Publish("goroutines", expvar.Func(func() any {
 		return runtime.NumGoroutine()
@@ -126,14 +287,49 @@ func main() {
 	}))
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.NewMailer(
-			cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender,
-		),
+		config:   cfg,
+		logger:   logger,
+		models:   data.NewModels(db),
+		mailer:   emailer,
+		webhooks: webhooks.NewClient(10 * time.Second),
+		limiter:  newClientLimiter(cfg.limiter.requestsPerSecond, cfg.limiter.burst),
+
+		instruments: instruments,
+		tracer:      tracer,
+	}
+
+	app.jobs = jobs.NewRunner(app.models.Jobs, logger, jobs.Config{
+		Workers:      cfg.jobs.workers,
+		PollInterval: cfg.jobs.pollInterval,
+		LockTimeout:  cfg.jobs.lockTimeout,
+		JobTimeout:   cfg.jobs.timeout,
+		MaxBackoff:   time.Hour,
+		OnResult:     instruments.observeJob,
+		Tracer:       tracer,
+	})
+	app.registerJobHandlers()
+
+	app.graphql, err = app.newGraphQLSchema()
+	if err != nil {
+		logger.PrintFatal(err, nil)
+		return
 	}
 
+	sinks, err := app.newOutboxSinks()
+	if err != nil {
+		logger.PrintFatal(err, nil)
+		return
+	}
+
+	app.outbox = outbox.NewRelay(app.models.Outbox, logger, outbox.Config{
+		PollInterval:   cfg.outbox.pollInterval,
+		BatchSize:      100,
+		PublishTimeout: 10 * time.Second,
+		Retention:      cfg.outbox.retention,
+	}, sinks...)
+
+	app.stream = stream.NewBroker(cfg.db.dsn, app.models.Outbox, logger)
+
 	err = app.serve()
 	if err != nil {
 		app.logger.PrintFatal(err, nil)
@@ -167,6 +363,67 @@ func openDB(cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
+func newMailTransport(cfg config, logger *jsonlog.Logger) (mailer.Transport, error) {
+	transport := cfg.mailer.transport
+	if transport == "" {
+		transport = "smtp"
+		if cfg.env == "development" {
+			transport = "log"
+		}
+	}
+
+	switch transport {
+	case "smtp":
+		if cfg.smtp.host == "" {
+			return nil, errors.New("smtp mail transport requires -smtp-host")
+		}
+		return mailer.NewSMTPTransport(
+			cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password,
+		), nil
+	case "file":
+		return mailer.NewFileTransport(cfg.mailer.dir)
+	case "log":
+		// the log transport writes out message bodies, activation tokens included, so it is kept
+		// away from environments whose logs are shipped anywhere
+		if cfg.env != "development" {
+			return nil, errors.New("log mail transport can only be used in development")
+		}
+		return mailer.NewLogTransport(logger), nil
+	case "memory":
+		return mailer.NewMemoryTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", transport)
+	}
+}
+
+func (app *application) newOutboxSinks() ([]outbox.Sink, error) {
+	sinks := []outbox.Sink{
+		outbox.SinkFunc{SinkName: "webhooks", Fn: app.publishWebhooks},
+	}
+
+	for _, name := range app.config.outbox.sinks {
+		switch name {
+		case "stdout":
+			sinks = append(sinks, outbox.NewStdoutSink())
+		case "file":
+			sink, err := outbox.NewFileSink(app.config.outbox.file)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case "http":
+			if app.config.outbox.url == "" {
+				return nil, errors.New("http outbox sink requires -outbox-url")
+			}
+			sinks = append(sinks, outbox.NewHTTPSink(app.config.outbox.url))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink: %s", name)
+		}
+	}
+
+	return sinks, nil
+}
+
 func mustAtoi(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil {
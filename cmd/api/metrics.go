@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+	"github.com/Yusufdot101/greenlight/internal/mailer"
+	"github.com/Yusufdot101/greenlight/internal/metrics"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+	"github.com/julienschmidt/httprouter"
+)
+
+// instruments are the Prometheus metrics served at /metrics.
+type instruments struct {
+	registry          *metrics.Registry
+	requests          *metrics.CounterVec
+	requestDuration   *metrics.HistogramVec
+	inFlight          metrics.Gauge
+	limiterRejections *metrics.CounterVec
+	mail              *metrics.CounterVec
+	jobs              *metrics.CounterVec
+	jobDuration       *metrics.HistogramVec
+}
+
+func newInstruments(db *sql.DB) *instruments {
+	registry := metrics.NewRegistry()
+
+	i := &instruments{
+		registry: registry,
+		requests: registry.NewCounterVec(
+			"greenlight_http_requests_total", "HTTP requests handled.", "route", "method", "status",
+		),
+		requestDuration: registry.NewHistogramVec(
+			"greenlight_http_request_duration_seconds", "Time taken to handle HTTP requests.",
+			metrics.DefBuckets, "route", "method", "status",
+		),
+		inFlight: registry.NewGaugeVec(
+			"greenlight_http_requests_in_flight", "HTTP requests currently being handled.",
+		).With(),
+		limiterRejections: registry.NewCounterVec(
+			"greenlight_rate_limiter_rejections_total",
+			"Requests turned away by the per-client rate limiter.", "server",
+		),
+		mail: registry.NewCounterVec(
+			"greenlight_mail_messages_total", "Emails handed to the mail transport.", "outcome",
+		),
+		jobs: registry.NewCounterVec(
+			"greenlight_jobs_total", "Background job attempts by how they ended.", "kind", "outcome",
+		),
+		jobDuration: registry.NewHistogramVec(
+			"greenlight_job_duration_seconds", "Time taken by background job attempts.",
+			[]float64{.01, .05, .1, .5, 1, 5, 10, 30, 60}, "kind",
+		),
+	}
+
+	gauge := func(name, help string, fn func(stats sql.DBStats) float64) {
+		registry.NewGaugeFunc(name, help, func() []metrics.Sample {
+			return []metrics.Sample{{Value: fn(db.Stats())}}
+		})
+	}
+	counter := func(name, help string, fn func(stats sql.DBStats) float64) {
+		registry.NewCounterFunc(name, help, func() []metrics.Sample {
+			return []metrics.Sample{{Value: fn(db.Stats())}}
+		})
+	}
+
+	gauge("greenlight_db_max_open_connections", "Maximum number of open connections.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("greenlight_db_open_connections", "Established connections, in use or idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("greenlight_db_in_use_connections", "Connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("greenlight_db_idle_connections", "Idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("greenlight_db_wait_count_total", "Connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("greenlight_db_wait_duration_seconds_total", "Time spent waiting for connections.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("greenlight_db_max_idle_closed_total",
+		"Connections closed because of the maximum idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("greenlight_db_max_idle_time_closed_total",
+		"Connections closed because of the maximum idle time.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("greenlight_db_max_lifetime_closed_total",
+		"Connections closed because of the maximum connection lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+
+	return i
+}
+
+func (i *instruments) observeJob(job *data.Job, outcome string, elapsed time.Duration) {
+	i.jobs.With(job.Kind, outcome).Inc()
+	i.jobDuration.With(job.Kind).Observe(elapsed.Seconds())
+}
+
+var routeContextKey = contextKey("route")
+
+// instrumentedRouter keeps a table of the route patterns registered with it, so that the route a
+// request is going to match is known before any middleware runs, and times handlers in a span.
+type instrumentedRouter struct {
+	*httprouter.Router
+	routes routeTable
+}
+
+func newInstrumentedRouter() instrumentedRouter {
+	return instrumentedRouter{Router: httprouter.New(), routes: routeTable{}}
+}
+
+func (router instrumentedRouter) Handler(method, path string, handler http.Handler) {
+	router.routes.add(method, path)
+	router.Router.Handler(method, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(
+			r.Context(), "handler "+method+" "+contextGetRoute(r), tracing.SpanKindInternal,
+		)
+		defer span.End()
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
+func (router instrumentedRouter) HandlerFunc(method, path string, handler http.HandlerFunc) {
+	router.Handler(method, path, handler)
+}
+
+// matchRoute records the pattern of the route a request is going to match, so that requests to
+// /v1/movies/1 and /v1/movies/2 are counted together under /v1/movies/:id, and adds it to the
+// request's log properties. Requests that match none get the route "unmatched".
+func (router instrumentedRouter) matchRoute(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		route := router.routes.match(r.Method, r.URL.Path)
+
+		ctx := context.WithValue(r.Context(), routeContextKey, route)
+		ctx = jsonlog.WithProperties(ctx, map[string]string{"route": route})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func contextGetRoute(r *http.Request) string {
+	route, ok := r.Context().Value(routeContextKey).(string)
+	if !ok {
+		return "unmatched"
+	}
+
+	return route
+}
+
+// routeTable holds the registered route patterns by method. httprouter does not say which
+// pattern a request matched, so the table matches them itself, the same way: a :name segment
+// matches any non-empty segment, and every other segment has to be equal.
+type routeTable map[string][]string
+
+func (table routeTable) add(method, pattern string) {
+	table[method] = append(table[method], pattern)
+}
+
+// match returns the pattern that matches path, preferring the one with the fewest parameters
+// when more than one does.
+func (table routeTable) match(method, path string) string {
+	segments := strings.Split(path, "/")
+
+	best, bestParams := "unmatched", -1
+	for _, pattern := range table[method] {
+		params, ok := matchPattern(strings.Split(pattern, "/"), segments)
+		if ok && (bestParams == -1 || params < bestParams) {
+			best, bestParams = pattern, params
+		}
+	}
+
+	return best
+}
+
+func matchPattern(pattern, segments []string) (int, bool) {
+	if len(pattern) != len(segments) {
+		return 0, false
+	}
+
+	params := 0
+	for i, part := range pattern {
+		switch {
+		case strings.HasPrefix(part, ":") && segments[i] != "":
+			params++
+		case part != segments[i]:
+			return 0, false
+		}
+	}
+
+	return params, true
+}
+
+type instrumentedTransport struct {
+	mailer.Transport
+	mail *metrics.CounterVec
+}
+
+func (transport instrumentedTransport) Send(msg *mailer.Message) error {
+	err := transport.Transport.Send(msg)
+	if err != nil {
+		transport.mail.With("failed").Inc()
+		return err
+	}
+
+	transport.mail.With("sent").Inc()
+	return nil
+}
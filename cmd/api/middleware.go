@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
 	"github.com/Yusufdot101/greenlight/internal/validator"
 	"github.com/felixge/httpsnoop"
 	"github.com/tomasen/realip"
@@ -31,53 +32,127 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
-func (app *application) rateLimiter(next http.Handler) http.Handler {
-	type client struct {
-		limiter  rate.Limiter
-		lastSeen time.Time
+// requestID tags every request with an ID that is echoed in the X-Request-ID response header and
+// used as the instance of error responses. A well-formed ID sent by the client, or by a proxy in
+// front of us, is reused so the request can be followed across services.
+func (app *application) requestID(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = contextSetRequestID(r, id)
+
+		ctx := jsonlog.WithProperties(r.Context(), map[string]string{
+			"request_id": id,
+			"method":     r.Method,
+			"url":        r.URL.String(),
+			"ip":         realip.FromRequest(r),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// producedMediaTypes are the representations served by handlers that write their own bodies
+// instead of going through writeResponse.
+var producedMediaTypes = []string{
+	"application/problem+json", "application/x-ndjson", "text/event-stream", "text/html",
+	"text/plain",
+}
+
+// requireAcceptable turns away requests whose Accept header rules out every representation the
+// API can produce, before a handler has done any work on their behalf.
+func (app *application) requireAcceptable(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+
+		for _, encoder := range encoders {
+			if acceptQuality(accept, encoder.contentType) > 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		for _, mediaType := range producedMediaTypes {
+			if acceptQuality(accept, mediaType) > 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		app.notAcceptableResponse(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+type clientLimiter struct {
+	requestsPerSecond float64
+	burst             int
+	clients           map[string]*limitedClient
+	mu                sync.Mutex
+}
+
+type limitedClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiter returns a per-IP token bucket limiter that is shared by the HTTP and gRPC
+// servers, so a client cannot double its allowance by switching between them.
+func newClientLimiter(requestsPerSecond float64, burst int) *clientLimiter {
+	limiter := &clientLimiter{
+		requestsPerSecond: requestsPerSecond,
+		burst:             burst,
+		clients:           make(map[string]*limitedClient),
 	}
-	var (
-		clients = make(map[string]*client)
-		mu      sync.Mutex
-	)
 
 	go func() {
 		for {
 			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, client := range clients {
+			limiter.mu.Lock()
+			for ip, client := range limiter.clients {
 				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(limiter.clients, ip)
 				}
 			}
-			mu.Unlock()
+			limiter.mu.Unlock()
 		}
 	}()
 
+	return limiter
+}
+
+func (limiter *clientLimiter) allow(ip string) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if _, exists := limiter.clients[ip]; !exists {
+		limiter.clients[ip] = &limitedClient{
+			limiter: rate.NewLimiter(rate.Limit(limiter.requestsPerSecond), limiter.burst),
+		}
+	}
+	limiter.clients[ip].lastSeen = time.Now()
+
+	return limiter.clients[ip].limiter.Allow()
+}
+
+func (app *application) rateLimiter(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if !app.config.limiter.enabled {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		ip := realip.FromRequest(r)
-
-		mu.Lock()
-		if _, exists := clients[ip]; !exists {
-			clients[ip] = &client{
-				limiter: *rate.NewLimiter(
-					rate.Limit(app.config.limiter.requestsPerSecond), app.config.limiter.burst,
-				),
-			}
-		}
-		clients[ip].lastSeen = time.Now()
-		if !clients[ip].limiter.Allow() {
-			mu.Unlock()
-			app.rateLimitExceededResponse(w)
+		if !app.limiter.allow(realip.FromRequest(r)) {
+			app.instruments.limiterRejections.With("http").Inc()
+			app.rateLimitExceededResponse(w, r)
 			return
 		}
 
-		mu.Unlock()
 		next.ServeHTTP(w, r)
 	}
 
@@ -97,7 +172,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		headParts := strings.Split(authorizationHeader, " ")
 		if len(headParts) != 2 || headParts[0] != "Bearer" {
-			app.invalidAuthenticationTokenResponse(w)
+			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
@@ -105,15 +180,15 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		v := validator.NewValidator()
 
 		if data.ValidateTokenPlaintext(v, token); !v.IsValid() {
-			app.invalidAuthenticationTokenResponse(w)
+			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		user, err := app.models.Users.GetUserForToken(data.ScopeAuthentication, token)
+		user, err := app.models.Users.GetUserForToken(r.Context(), data.ScopeAuthentication, token)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrNoRecord):
-				app.invalidAuthenticationTokenResponse(w)
+				app.invalidAuthenticationTokenResponse(w, r)
 			default:
 				app.serverError(w, r, err)
 			}
@@ -131,7 +206,7 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 		if !user.Activated {
-			app.inactiveAccountResponse(w)
+			app.inactiveAccountResponse(w, r)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -144,7 +219,7 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 		if user.IsAnonymous() {
-			app.authenticationRequiredResponse(w)
+			app.authenticationRequiredResponse(w, r)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -158,13 +233,13 @@ func (app *application) requirePermission(
 ) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
-		permissions, err := app.models.Permissions.GellAllForUser(user.ID)
+		permissions, err := app.models.Permissions.GellAllForUser(r.Context(), user.ID)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 		if !permissions.Include(permission) {
-			app.notPermittedResponse(w)
+			app.notPermittedResponse(w, r)
 			return
 		}
 
@@ -180,11 +255,16 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 		origin := r.Header.Get("Origin")
 		if slices.Contains(app.config.cors.trustedOrigins, origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID, Idempotent-Replayed")
 			if r.Method == http.MethodOptions &&
 				r.Header.Get("Access-Control-Request-Method") != "" {
 
 				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set(
+					"Access-Control-Allow-Headers",
+					"Authorization, Content-Type, If-Match, If-None-Match, X-Request-ID, "+
+						"Idempotency-Key",
+				)
 
 				w.WriteHeader(http.StatusOK)
 				return
@@ -204,6 +284,8 @@ func (app *application) metrics(next http.Handler) http.Handler {
 
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		totalRequestsRecieved.Add(1)
+		app.instruments.inFlight.Inc()
+		defer app.instruments.inFlight.Dec()
 
 		metrics := httpsnoop.CaptureMetrics(next, w, r)
 
@@ -211,7 +293,14 @@ func (app *application) metrics(next http.Handler) http.Handler {
 
 		totalProcessincTimeMicrosecond.Add(metrics.Duration.Microseconds())
 
-		totalResponsesSendByStatus.Add(strconv.Itoa(metrics.Code), 1)
+		status := strconv.Itoa(metrics.Code)
+		totalResponsesSendByStatus.Add(status, 1)
+
+		route := contextGetRoute(r)
+		app.instruments.requests.With(route, r.Method, status).Inc()
+		app.instruments.requestDuration.With(route, r.Method, status).Observe(
+			metrics.Duration.Seconds(),
+		)
 	}
 
 	return http.HandlerFunc(fn)
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonpatch"
 	"github.com/Yusufdot101/greenlight/internal/validator"
 )
 
@@ -18,7 +26,7 @@ func (app *application) createMovie(w http.ResponseWriter, r *http.Request) {
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -34,18 +42,20 @@ func (app *application) createMovie(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.NewValidator()
 	if data.ValidateMovie(v, movie); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.InsertMovie(movie)
+	err = app.models.Movies.InsertMovie(r.Context(), movie)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(
-		w, http.StatusCreated, envelope{
+	app.outbox.Notify()
+
+	err = app.writeMovieResponse(
+		w, r, http.StatusCreated, movie, nil, envelope{
 			"message": "movie created successfully",
 			"movie":   movie,
 		},
@@ -62,7 +72,20 @@ func (app *application) GetMovieByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	movie, err := app.models.Movies.GetByID(id)
+	fields := app.readCSV(r.URL.Query(), "fields", []string{})
+
+	v := validator.NewValidator()
+	if data.ValidateFields(v, fields, data.MovieFieldsSafeList); !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	columns := fields
+	if len(fields) > 0 {
+		columns = slices.Concat(fields, []string{"id", "version"})
+	}
+
+	movie, err := app.models.Movies.GetByID(r.Context(), id, columns...)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
@@ -73,20 +96,125 @@ func (app *application) GetMovieByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie})
+	shaped, err := app.pickFields(movie, fields)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeMovieResponse(w, r, http.StatusOK, movie, fields, envelope{"movie": shaped})
 	if err != nil {
 		app.serverError(w, r, err)
 	}
 }
 
 func (app *application) updateMovie(w http.ResponseWriter, r *http.Request) {
+	movie, conditional, ok := app.readMovieForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/merge-patch+json":
+		var patch any
+		err := app.readJSON(w, r, &patch)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ok = app.patchMovie(w, r, movie, func(doc any) (any, error) {
+			return jsonpatch.MergePatch(doc, patch), nil
+		})
+	case "application/json-patch+json":
+		var ops []jsonpatch.Operation
+		err := app.readJSON(w, r, &ops)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ok = app.patchMovie(w, r, movie, func(doc any) (any, error) {
+			return jsonpatch.Apply(doc, ops)
+		})
+	case "", "application/json":
+		var input struct {
+			Title   string       `json:"title"`
+			Runtime data.Runtime `json:"runtime"`
+			Year    int32        `json:"year"`
+			Genres  []string     `json:"genres"`
+		}
+
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Title != "" {
+			movie.Title = input.Title
+		}
+		if input.Runtime != 0 {
+			movie.Runtime = input.Runtime
+		}
+		if input.Year != 0 {
+			movie.Year = input.Year
+		}
+		if input.Genres != nil {
+			movie.Genres = input.Genres
+		}
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	app.saveMovie(w, r, movie, conditional)
+}
+
+func (app *application) replaceMovie(w http.ResponseWriter, r *http.Request) {
+	movie, conditional, ok := app.readMovieForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title   string       `json:"title"`
+		Runtime data.Runtime `json:"runtime"`
+		Year    int32        `json:"year"`
+		Genres  []string     `json:"genres"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movie.Title = input.Title
+	movie.Runtime = input.Runtime
+	movie.Year = input.Year
+	movie.Genres = input.Genres
+
+	app.saveMovie(w, r, movie, conditional)
+}
+
+// readMovieForWrite loads the movie named in the URL and checks the request's If-Match
+// precondition against it, sending the error response itself when either fails.
+func (app *application) readMovieForWrite(
+	w http.ResponseWriter, r *http.Request,
+) (movie *data.Movie, conditional, ok bool) {
 	id, err := app.readParamID(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
-		return
+		return nil, false, false
 	}
 
-	movie, err := app.models.Movies.GetByID(id)
+	movie, err = app.models.Movies.GetByID(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
@@ -94,54 +222,129 @@ func (app *application) updateMovie(w http.ResponseWriter, r *http.Request) {
 		default:
 			app.serverError(w, r, err)
 		}
-		return
+		return nil, false, false
 	}
 
-	var input struct {
+	ok, conditional = app.checkIfMatch(w, r, movie)
+	if !ok {
+		return nil, false, false
+	}
+
+	return movie, conditional, true
+}
+
+// patchMovie applies patch to the JSON representation of movie, the same document clients get
+// from GET /v1/movies/:id, and copies the editable fields of the result back onto movie. It
+// sends the error response itself and returns false when the patch cannot be applied.
+func (app *application) patchMovie(
+	w http.ResponseWriter, r *http.Request, movie *data.Movie, patch func(doc any) (any, error),
+) bool {
+	original, err := json.Marshal(movie)
+	if err != nil {
+		panic(err)
+	}
+
+	var doc any
+	err = json.Unmarshal(original, &doc)
+	if err != nil {
+		panic(err)
+	}
+
+	doc, err = patch(doc)
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			app.patchTestFailedResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return false
+	}
+
+	patched, ok := doc.(map[string]any)
+	if !ok {
+		app.badRequestResponse(w, r, errors.New("patched movie must be a JSON object"))
+		return false
+	}
+
+	v := validator.NewValidator()
+	v.CheckAdd(patched["id"] == float64(movie.ID), "id", "cannot be changed")
+	v.CheckAdd(patched["version"] == float64(movie.Version), "version", "cannot be changed")
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+	delete(patched, "id")
+	delete(patched, "version")
+
+	var fields struct {
 		Title   string       `json:"title"`
 		Runtime data.Runtime `json:"runtime"`
 		Year    int32        `json:"year"`
 		Genres  []string     `json:"genres"`
 	}
 
-	err = app.readJSON(w, r, &input)
+	JSON, err := json.Marshal(patched)
 	if err != nil {
-		app.badRequestResponse(w, err)
-		return
+		panic(err)
 	}
 
-	if input.Title != "" {
-		movie.Title = input.Title
-	}
-	if input.Runtime != 0 {
-		movie.Runtime = input.Runtime
-	}
-	if input.Year != 0 {
-		movie.Year = input.Year
-	}
-	if input.Genres != nil {
-		movie.Genres = input.Genres
+	decoder := json.NewDecoder(bytes.NewReader(JSON))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&fields)
+	if err != nil {
+		var unmarshalTypeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &unmarshalTypeErr):
+			app.badRequestResponse(
+				w, r, fmt.Errorf("patched movie has an invalid type for %s", unmarshalTypeErr.Field),
+			)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			app.badRequestResponse(w, r, fmt.Errorf(
+				"patched movie contains unknown key: %s",
+				strings.TrimPrefix(err.Error(), "json: unknown field "),
+			))
+		default:
+			app.badRequestResponse(w, r, fmt.Errorf("patched movie is invalid: %w", err))
+		}
+		return false
 	}
 
+	movie.Title = fields.Title
+	movie.Runtime = fields.Runtime
+	movie.Year = fields.Year
+	movie.Genres = fields.Genres
+
+	return true
+}
+
+func (app *application) saveMovie(
+	w http.ResponseWriter, r *http.Request, movie *data.Movie, conditional bool,
+) {
 	v := validator.NewValidator()
 	if data.ValidateMovie(v, movie); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.UpdateMovie(movie)
+	err := app.models.Movies.UpdateMovie(r.Context(), movie)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrEditConflic) && conditional:
+			app.preconditionFailedResponse(w, r)
 		case errors.Is(err, data.ErrEditConflic):
-			app.editConflictResponse(w)
+			app.editConflictResponse(w, r)
 		default:
 			app.serverError(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(
-		w, http.StatusOK, envelope{
+	app.outbox.Notify()
+
+	err = app.writeMovieResponse(
+		w, r, http.StatusOK, movie, nil, envelope{
 			"message": "movie updated successfully",
 			"movie":   movie,
 		},
@@ -158,61 +361,184 @@ func (app *application) DeleteMovieByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.models.Movies.DeleteByID(id)
+	if r.Header.Get("If-Match") != "" || app.config.requireIfMatch {
+		movie, err := app.models.Movies.GetByID(r.Context(), id, "id", "version")
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		ok, _ := app.checkIfMatch(w, r, movie)
+		if !ok {
+			return
+		}
+
+		err = app.models.Movies.DeleteVersion(r.Context(), id, movie.Version)
+	} else {
+		err = app.models.Movies.DeleteByID(r.Context(), id)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflic):
+			app.preconditionFailedResponse(w, r)
 		default:
 			app.serverError(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie deleted successfully"})
+	app.outbox.Notify()
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie deleted successfully"})
 	if err != nil {
 		app.serverError(w, r, err)
 	}
 }
 
+var movieSafeSortList = []string{
+	"id", "-id",
+	"title", "-title",
+	"runtime", "-runtime",
+	"year", "-year",
+	"genres", "-genres",
+}
+
+func (app *application) readMovieQuery(qs url.Values, v *validator.Validator) data.MovieQuery {
+	var q data.MovieQuery
+
+	q.Title = app.readString(qs, "title", "")
+	search := app.readString(qs, "search", "fulltext")
+	q.Year = app.readInt(qs, "year", 0, v)
+	q.YearMin = app.readInt(qs, "year_min", 0, v)
+	q.YearMax = app.readInt(qs, "year_max", 0, v)
+	q.RuntimeMin = app.readInt(qs, "runtime_min", 0, v)
+	q.RuntimeMax = app.readInt(qs, "runtime_max", 0, v)
+	q.Genres = app.readCSV(qs, "genres", []string{})
+	q.GenresAny = app.readCSV(qs, "genres_any", []string{})
+	q.ExcludeGenres = app.readCSV(qs, "exclude_genres", []string{})
+	q.CreatedAfter = app.readTime(qs, "created_after", v)
+	q.CreatedBefore = app.readTime(qs, "created_before", v)
+
+	v.CheckAdd(
+		validator.ValueInList(search, "fulltext", "fuzzy"), "search",
+		"must be either fulltext or fuzzy",
+	)
+	q.Fuzzy = search == "fuzzy"
+
+	data.ValidateMovieQuery(v, &q)
+
+	return q
+}
+
 func (app *application) ListMovies(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Year   int
-		Genres []string
+		Facets []string
+		Fields []string
+		data.MovieQuery
 		data.Filter
 	}
 
 	qs := r.URL.Query()
 	v := validator.NewValidator()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Year = app.readInt(qs, "year", -1, v)
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieQuery = app.readMovieQuery(qs, v)
+	input.Facets = app.readCSV(qs, "facets", []string{})
+	input.Fields = app.readCSV(qs, "fields", []string{})
 	input.Page = app.readInt(qs, "page", 1, v)
 	input.PageSize = app.readInt(qs, "page_size", 100, v)
 	input.Sort = app.readString(qs, "sort", "id")
-	input.SafeSortList = []string{
-		"id", "-id",
-		"title", "-title",
-		"runtime", "-runtime",
-		"year", "-year",
-		"genres", "-genres",
+	input.SafeSortList = movieSafeSortList
+
+	for _, facet := range input.Facets {
+		v.CheckAdd(
+			validator.ValueInList(facet, data.FacetsSafeList...), "facets",
+			"must only contain genres, year or decade",
+		)
 	}
+	v.CheckAdd(validator.ListUnique(input.Facets...), "facets", "cannot have duplicates")
 
+	data.ValidateFields(v, input.Fields, data.MovieFieldsSafeList)
 	if data.ValidateFilters(v, &input.Filter); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.ListMovies(input.Title, input.Year, input.Genres, input.Filter)
+	movies, metadata, err := app.models.Movies.ListMovies(
+		r.Context(), input.MovieQuery, input.Filter, input.Fields...,
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	app.writeJSON(
-		w, http.StatusOK, envelope{
-			"metadata": metadata, "movies": movies,
-		},
-	)
+
+	shaped, err := app.pickFields(movies, input.Fields)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	env := envelope{"metadata": metadata, "movies": shaped}
+
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movies.Facets(r.Context(), input.MovieQuery, input.Facets)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidFacet):
+				app.badRequestResponse(w, r, err)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		env["facets"] = facets
+	}
+
+	if len(movies) == 0 && input.Title != "" {
+		suggestion, err := app.models.Movies.SuggestTitle(r.Context(), input.Title)
+		switch {
+		case err == nil:
+			env["did_you_mean"] = suggestion
+		case !errors.Is(err, data.ErrNoRecord):
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.writeResponse(w, r, http.StatusOK, env)
+}
+
+func (app *application) autocompleteMovies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	prefix := strings.TrimSpace(qs.Get("q"))
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.CheckAdd(prefix != "", "q", "must be provided")
+	v.CheckAdd(len(prefix) <= 500, "q", "cannot be more than 500 characters")
+	v.CheckAdd(limit > 0, "limit", "must be postive integer")
+	v.CheckAdd(limit <= 20, "limit", "cannot exceed 20")
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	matches, err := app.models.Movies.Autocomplete(r.Context(), prefix, limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movies": matches})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+)
+
+func TestMovieETag(t *testing.T) {
+	type representation struct {
+		version     int32
+		contentType string
+		fields      []string
+		pretty      bool
+	}
+
+	etag := func(rep representation) string {
+		return movieETag(&data.Movie{ID: 7, Version: rep.version}, rep.contentType, rep.fields,
+			rep.pretty)
+	}
+
+	original := representation{2, "application/json", []string{"title", "year"}, false}
+
+	tests := []struct {
+		name     string
+		change   func(rep *representation)
+		wantSame bool
+	}{
+		{"same representation", func(rep *representation) {}, true},
+		{
+			"fields in another order",
+			func(rep *representation) { rep.fields = []string{"year", "title", "year"} },
+			true,
+		},
+		{
+			"other content type",
+			func(rep *representation) { rep.contentType = "application/msgpack" },
+			false,
+		},
+		{"other fields", func(rep *representation) { rep.fields = []string{"title"} }, false},
+		{"all fields", func(rep *representation) { rep.fields = nil }, false},
+		{"pretty", func(rep *representation) { rep.pretty = true }, false},
+		{"other version", func(rep *representation) { rep.version = 3 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := original
+			tt.change(&rep)
+
+			if same := etag(rep) == etag(original); same != tt.wantSame {
+				t.Errorf("got same ETag %t; want %t", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestETagMatchesMovie(t *testing.T) {
+	movie := &data.Movie{ID: 7, Version: 2}
+	msgpackETag := movieETag(movie, "application/msgpack", []string{"title"}, false)
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"any representation", msgpackETag, true},
+		{"one of several", `"7-1-abcd", ` + msgpackETag, true},
+		{"version only", `"7-2"`, true},
+		{"wildcard", "*", true},
+		{"stale version", `"7-1-abcd"`, false},
+		{"other movie", `"17-2-abcd"`, false},
+		{"weak", "W/" + msgpackETag, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatchesMovie(tt.header, movie); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMovieResponseNotModified(t *testing.T) {
+	movie := &data.Movie{ID: 7, Title: "Moana", Version: 2}
+
+	get := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		app := &application{}
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/movies/7", nil)
+		r.Header.Set("Accept", accept)
+		r.Header.Set("If-None-Match", ifNoneMatch)
+		rr := httptest.NewRecorder()
+
+		err := app.writeMovieResponse(rr, r, http.StatusOK, movie, nil, envelope{"movie": movie})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr
+	}
+
+	jsonETag := get("application/json", "").Header().Get("ETag")
+
+	tests := []struct {
+		name        string
+		accept      string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"same representation", "application/json", jsonETag, http.StatusNotModified},
+		{"weak match", "application/json", "W/" + jsonETag, http.StatusNotModified},
+		{"other representation", "application/msgpack", jsonETag, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(tt.accept, tt.ifNoneMatch)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("got Vary %q; want %q", got, "Accept")
+			}
+			if rr.Header().Get("ETag") == "" {
+				t.Error("got no ETag")
+			}
+		})
+	}
+}
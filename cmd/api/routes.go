@@ -3,12 +3,13 @@ package main
 import (
 	"expvar"
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 func (app *application) routes() http.Handler {
-	router := httprouter.New()
+	router := newInstrumentedRouter()
 
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
@@ -19,14 +20,37 @@ func (app *application) routes() http.Handler {
 		http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.ListMovies),
 	)
 
-	router.HandlerFunc(
-		http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.GetMovieByID),
+	router.staticSegments(
+		http.MethodGet, "/v1/movies/:id",
+		map[string]http.HandlerFunc{
+			"autocomplete": app.requirePermission("movies:read", app.autocompleteMovies),
+			"export":       app.requirePermission("movies:export", app.exportMovies),
+			"stream":       app.requirePermission("movies:read", app.streamMovies),
+		},
+		app.requirePermission("movies:read", app.GetMovieByID),
 	)
 
 	router.HandlerFunc(
 		http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovie),
 	)
 
+	router.HandlerFunc(
+		http.MethodPatch, "/v1/movies", app.requirePermission("movies:write", app.bulkUpdateMovies),
+	)
+
+	router.HandlerFunc(
+		http.MethodDelete, "/v1/movies", app.requirePermission("movies:write", app.bulkDeleteMovies),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost, "/v1/movies/import",
+		app.requirePermission("movies:write", app.importMovies),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost, "/v1/movies/batch", app.requirePermission("movies:write", app.batchMovies),
+	)
+
 	router.HandlerFunc(
 		http.MethodDelete, "/v1/movies/:id",
 		app.requirePermission("movies:write", app.DeleteMovieByID),
@@ -36,6 +60,16 @@ func (app *application) routes() http.Handler {
 		http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updateMovie),
 	)
 
+	router.HandlerFunc(
+		http.MethodPut, "/v1/movies/:id", app.requirePermission("movies:write", app.replaceMovie),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet, "/v1/imports/:id", app.requirePermission("movies:write", app.showImportHandler),
+	)
+
+	router.HandlerFunc(http.MethodPost, "/v1/graphql", app.graphqlHandler)
+
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
@@ -44,7 +78,102 @@ func (app *application) routes() http.Handler {
 		http.MethodPut, "/v1/tokens/authentication", app.createAuthenticationTokenHandler,
 	)
 
-	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
+	router.HandlerFunc(
+		http.MethodGet, "/v1/admin/jobs", app.requirePermission("jobs:read", app.listJobsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet, "/v1/admin/jobs/:id", app.requirePermission("jobs:read", app.showJobHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost, "/v1/admin/jobs/:id/retry",
+		app.requirePermission("jobs:write", app.retryJobHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet, "/v1/admin/emails/:template/preview",
+		app.requirePermission("emails:read", app.previewEmailHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet, "/v1/admin/webhooks",
+		app.requirePermission("webhooks:read", app.listWebhooksHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost, "/v1/admin/webhooks",
+		app.requirePermission("webhooks:write", app.createWebhookHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodDelete, "/v1/admin/webhooks/:id",
+		app.requirePermission("webhooks:write", app.deleteWebhookHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet, "/v1/admin/webhooks/:id/deliveries",
+		app.requirePermission("webhooks:read", app.listWebhookDeliveriesHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost, "/v1/admin/webhooks/:id/deliveries/:delivery_id/replay",
+		app.requirePermission("webhooks:write", app.replayWebhookDeliveryHandler),
+	)
+
+	if app.config.metrics.port == 0 {
+		router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
+		router.Handler(http.MethodGet, "/metrics", app.instruments.registry.Handler())
+	}
+
+	middleware := []struct {
+		name string
+		fn   func(http.Handler) http.Handler
+	}{
+		{"metrics", app.metrics},
+		{"recoverPanic", app.recoverPanic},
+		{"enableCORS", app.enableCORS},
+		{"requireAcceptable", app.requireAcceptable},
+		{"rateLimiter", app.rateLimiter},
+		{"authenticate", app.authenticate},
+		{"idempotency", app.idempotency},
+	}
+
+	var handler http.Handler = router
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = app.traced(middleware[i].name, middleware[i].fn)(handler)
+	}
+
+	return app.requestID(router.matchRoute(app.startTrace(handler)))
+}
+
+// adminRoutes serves the operational endpoints on their own port when -metrics-port is set, so
+// they can be kept off the public network.
+func (app *application) adminRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /metrics", app.instruments.registry.Handler())
+
+	return mux
+}
+
+// staticSegments routes requests whose :id parameter names a fixed sub-resource, such as
+// /v1/movies/autocomplete, because httprouter will not register a static segment alongside a
+// named parameter at the same position. The sub-resources are still added to the route table, so
+// they are counted, traced and logged under their own paths rather than under path.
+func (router instrumentedRouter) staticSegments(
+	method, path string, handlers map[string]http.HandlerFunc, next http.HandlerFunc,
+) {
+	for segment := range handlers {
+		router.routes.add(method, strings.Replace(path, ":id", segment, 1))
+	}
 
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimiter(app.authenticate(router)))))
+	router.HandlerFunc(method, path, func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+		if handler, ok := handlers[params.ByName("id")]; ok {
+			handler(w, r)
+			return
+		}
+		next(w, r)
+	})
 }
@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 func (app *application) serve() error {
@@ -19,6 +22,31 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	var grpcSrv *grpc.Server
+	var grpcListener net.Listener
+	if app.config.grpc.port != 0 {
+		var err error
+		grpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", app.config.grpc.port))
+		if err != nil {
+			return err
+		}
+		grpcSrv = app.newGRPCServer()
+	}
+
+	var adminSrv *http.Server
+	if app.config.metrics.port != 0 {
+		adminSrv = &http.Server{
+			Addr:         fmt.Sprintf(":%d", app.config.metrics.port),
+			Handler:      app.adminRoutes(),
+			IdleTimeout:  1 * time.Minute,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+	}
+
+	// open event streams never go idle on their own, so end them as soon as shutdown begins
+	srv.RegisterOnShutdown(app.stream.Close)
+
 	shutdownErr := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -38,18 +66,101 @@ func (app *application) serve() error {
 			shutdownErr <- err
 		}
 
+		if adminSrv != nil {
+			err = adminSrv.Shutdown(ctx)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"stage": "stopping admin server"})
+			}
+		}
+
+		if grpcSrv != nil {
+			stopped := make(chan struct{})
+			go func() {
+				grpcSrv.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcSrv.Stop()
+			}
+		}
+
+		app.logger.PrintIfo("stopping outbox relay", nil)
+		outboxCtx, outboxCancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer outboxCancel()
+
+		err = app.outbox.Shutdown(outboxCtx)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"stage": "stopping outbox relay"})
+		}
+
+		app.logger.PrintIfo("draining job workers", nil)
+		jobsCtx, jobsCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer jobsCancel()
+
+		err = app.jobs.Shutdown(jobsCtx)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"stage": "draining job workers"})
+		}
+
 		app.logger.PrintIfo("finishing background tasks", nil)
 		app.wg.Wait()
+
+		app.logger.PrintIfo("flushing spans", nil)
+		tracerCtx, tracerCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer tracerCancel()
+
+		err = app.tracer.Shutdown(tracerCtx)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"stage": "flushing spans"})
+		}
+
 		shutdownErr <- nil
 	}()
 
+	err := app.stream.Start()
+	if err != nil {
+		return err
+	}
+
+	go app.tracer.Run()
+	app.jobs.Start()
+	app.outbox.Start()
+	app.pruneIdempotencyKeys()
+
+	if grpcSrv != nil {
+		go func() {
+			err := grpcSrv.Serve(grpcListener)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"stage": "serving gRPC"})
+			}
+		}()
+
+		app.logger.PrintIfo("starting gRPC server", map[string]string{
+			"addr": grpcListener.Addr().String(),
+		})
+	}
+
+	if adminSrv != nil {
+		go func() {
+			err := adminSrv.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				app.logger.PrintError(err, map[string]string{"stage": "serving admin endpoints"})
+			}
+		}()
+
+		app.logger.PrintIfo("starting admin server", map[string]string{"addr": adminSrv.Addr})
+	}
+
 	app.logger.PrintIfo(
 		"starting server", map[string]string{
 			"env":  app.config.env,
 			"addr": fmt.Sprintf(":%d", app.config.port),
 		},
 	)
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	if err != http.ErrServerClosed {
 		return err
 	}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+	streamRetry             = 3 * time.Second
+	streamResumeLimit       = 1000
+)
+
+func (app *application) streamMovies(w http.ResponseWriter, r *http.Request) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	var after int64
+	if lastEventID != "" {
+		var err error
+		after, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || after < 0 {
+			app.badRequestResponse(w, r, errors.New("Last-Event-ID must be a positive integer"))
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	// the server's write timeout would otherwise cut every stream off after 30 seconds
+	err := rc.SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// subscribe before reading the backlog so nothing committed in between is missed
+	sub := app.stream.Subscribe()
+	defer app.stream.Unsubscribe(sub)
+
+	var backlog []*data.OutboxEvent
+	if lastEventID != "" {
+		backlog, err = app.models.Outbox.EventsAfter(
+			r.Context(), "movie", after, streamResumeLimit+1,
+		)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	sent := make(map[int64]bool, len(backlog))
+	if len(backlog) > streamResumeLimit {
+		// the client is further behind than the log we are willing to replay, so tell it to
+		// reload its state and carry on from live events
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	} else {
+		for _, event := range backlog {
+			err = writeStreamEvent(w, event)
+			if err != nil {
+				return
+			}
+			sent[event.PublishedSequence] = true
+		}
+	}
+
+	err = rc.Flush()
+	if err != nil {
+		return
+	}
+
+	user := app.contextGetUser(r)
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case event := <-sub.Events:
+			if event.Aggregate != "movie" || sent[event.PublishedSequence] {
+				continue
+			}
+
+			err = writeStreamEvent(w, event)
+		case <-heartbeat.C:
+			// permissions can be revoked while a stream is open, so check them again before
+			// keeping it alive
+			permissions, permissionsErr := app.models.Permissions.GellAllForUser(
+				r.Context(), user.ID,
+			)
+			if permissionsErr != nil {
+				app.logError(r.Context(), permissionsErr, nil)
+				return
+			}
+			if !permissions.Include("movies:read") {
+				fmt.Fprint(w, "event: revoked\ndata: {}\n\n")
+				rc.Flush()
+				return
+			}
+
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+
+		err = rc.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event *data.OutboxEvent) error {
+	JSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(
+		w, "id: %d\nevent: %s\ndata: %s\n\n", event.PublishedSequence, event.Type, JSON,
+	)
+	return err
+}
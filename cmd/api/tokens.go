@@ -17,7 +17,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -25,15 +25,15 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	data.ValidateEmail(v, input.Email)
 	data.ValidateEmail(v, input.Email)
 	if !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	user, err := app.models.Users.GetUserByEmail(input.Email)
+	user, err := app.models.Users.GetUserByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
-			app.invalidCredentialsResponse(w)
+			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverError(w, r, err)
 		}
@@ -47,17 +47,19 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !matches {
-		app.invalidCredentialsResponse(w)
+		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := app.models.Tokens.NewToken(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	token, err := app.models.Tokens.NewToken(
+		r.Context(), user.ID, 24*time.Hour, data.ScopeAuthentication,
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"authentication_token": token})
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"authentication_token": token})
 	if err != nil {
 		app.serverError(w, r, err)
 	}
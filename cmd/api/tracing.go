@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+	"github.com/felixge/httpsnoop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTraceExporter(cfg config) (tracing.Exporter, error) {
+	resource := []tracing.Attribute{
+		tracing.String("service.name", "greenlight"),
+		tracing.String("service.version", version),
+		tracing.String("deployment.environment.name", cfg.env),
+	}
+
+	switch cfg.trace.exporter {
+	case "none":
+		return nil, nil
+	case "stdout":
+		return tracing.NewJSONExporter(os.Stdout, resource...), nil
+	case "file":
+		file, err := os.OpenFile(cfg.trace.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return tracing.NewJSONExporter(file, resource...), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.trace.exporter)
+	}
+}
+
+// startTrace starts the server span of a request, continuing the caller's trace when it sends a
+// valid traceparent header.
+func (app *application) startTrace(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, parent)
+		}
+
+		ctx, span := app.tracer.Start(
+			ctx, r.Method, tracing.SpanKindServer,
+			tracing.String("http.request.method", r.Method),
+			tracing.String("url.path", r.URL.Path),
+			tracing.String("user_agent.original", r.UserAgent()),
+			tracing.String("greenlight.request_id", contextGetRequestID(r)),
+		)
+		defer span.End()
+
+		if route := contextGetRoute(r); route != "unmatched" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(tracing.String("http.route", route))
+		}
+
+		metrics := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.response.status_code", metrics.Code))
+		if metrics.Code >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(metrics.Code))
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+var tracedParentContextKey = contextKey("traced_parent")
+
+// traced wraps a middleware so that its own work, up to the point where it hands the request on,
+// is timed in a span of its own. Whatever runs after that gets the request's span back as its
+// parent, so the middleware spans sit side by side instead of nesting.
+func (app *application) traced(
+	name string, middleware func(http.Handler) http.Handler,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tracing.SpanFromContext(r.Context()).End()
+
+			parent, _ := r.Context().Value(tracedParentContextKey).(*tracing.Span)
+			next.ServeHTTP(w, r.WithContext(tracing.ContextWithSpan(r.Context(), parent)))
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := tracing.SpanFromContext(r.Context())
+			if parent == nil {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), tracedParentContextKey, parent)
+			ctx, span := tracing.Start(ctx, "middleware "+name, tracing.SpanKindInternal)
+			defer span.End()
+
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (app *application) grpcTrace(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("traceparent"); len(values) == 1 {
+		if parent, ok := tracing.ParseTraceparent(values[0]); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, parent)
+		}
+	}
+
+	ctx, span := app.tracer.Start(
+		ctx, info.FullMethod, tracing.SpanKindServer,
+		tracing.String("rpc.system", "grpc"),
+		tracing.String("rpc.method", info.FullMethod),
+	)
+	defer span.End()
+
+	res, err := handler(ctx, req)
+
+	code := status.Code(err)
+	span.SetAttributes(tracing.Int("rpc.grpc.status_code", int(code)))
+	if code == codes.Internal || code == codes.Unknown {
+		span.SetError(code.String())
+	}
+
+	return res, err
+}
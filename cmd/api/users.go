@@ -3,7 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/Yusufdot101/greenlight/internal/data"
 	"github.com/Yusufdot101/greenlight/internal/validator"
@@ -14,11 +14,12 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"`
 	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -26,6 +27,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name:      input.Name,
 		Email:     input.Email,
 		Activated: false,
+		Locale:    strings.ToLower(input.Locale),
 	}
 	err = user.Password.Set(input.Password)
 	if err != nil {
@@ -35,16 +37,26 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	v := validator.NewValidator()
 	if data.ValidateUser(v, user); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Users.InsertUser(user)
+	// the user, their permission and their welcome email are committed together, so a failure
+	// here leaves nothing behind and the client can retry the registration
+	newWelcomeJob := func(user *data.User) (*data.Job, error) {
+		payload := userWelcomeEmailPayload{
+			UserID:  user.ID,
+			Locales: app.readAcceptLanguage(r),
+		}
+		return app.newJob(r.Context(), jobUserWelcomeEmail, payload, 10)
+	}
+
+	err = app.models.Users.Register(r.Context(), user, []string{"movies:read"}, newWelcomeJob)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
 			v.AddError("email", "a user with email address already exists")
-			app.failedValidationResponse(w, v.Errors)
+			app.failedValidationResponse(w, r, v.Errors)
 
 		default:
 			app.serverError(w, r, err)
@@ -52,33 +64,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "movies:read")
-	if err != nil {
-		app.serverError(w, r, err)
-		return
-	}
-
-	token, err := app.models.Tokens.NewToken(user.ID, 3*24*time.Hour, data.ScopeActivation)
-	if err != nil {
-		app.serverError(w, r, err)
-		return
-	}
-
-	data := map[string]any{
-		"useID":           user.ID,
-		"activationToken": token.Plaintext,
-	}
-
-	fn := func() {
-		err := app.mailer.Send(user.Email, "user_welcome.tmpl.html", data)
-		if err != nil {
-			app.logger.PrintError(err, nil)
-		}
-	}
-	app.background(fn)
-
-	err = app.writeJSON(
-		w,
+	err = app.writeResponse(
+		w, r,
 		http.StatusAccepted,
 		envelope{
 			"message": "user created successfully",
@@ -97,22 +84,22 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
 	v := validator.NewValidator()
 	if data.ValidateTokenPlaintext(v, input.Token); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	user, err := app.models.Users.GetUserForToken(data.ScopeActivation, input.Token)
+	user, err := app.models.Users.GetUserForToken(r.Context(), data.ScopeActivation, input.Token)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
 			v.AddError("token", "invaild or expired token")
-			app.failedValidationResponse(w, v.Errors)
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverError(w, r, err)
 		}
@@ -120,25 +107,25 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	user.Activated = true
-	err = app.models.Users.UpadeteUser(user)
+	err = app.models.Users.UpadeteUser(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflic):
-			app.editConflictResponse(w)
+			app.editConflictResponse(w, r)
 		default:
 			app.serverError(w, r, err)
 		}
 		return
 	}
 
-	err = app.models.Tokens.DeleteAllForUser(user.ID, data.ScopeActivation)
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), user.ID, data.ScopeActivation)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(
-		w,
+	err = app.writeResponse(
+		w, r,
 		http.StatusAccepted,
 		envelope{
 			"message": "user activated successfully",
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/validator"
+	"github.com/Yusufdot101/greenlight/internal/webhooks"
+	"github.com/julienschmidt/httprouter"
+)
+
+const jobWebhookDelivery = "webhook_delivery"
+
+type webhookDeliveryPayload struct {
+	WebhookID int64           `json:"webhook_id"`
+	EventID   string          `json:"event_id"`
+	Event     string          `json:"event"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// publishWebhooks is the outbox sink that fans an event out to a delivery job per subscribed
+// webhook. The outbox may hand over the same event twice, in which case receivers see the same
+// event ID again.
+func (app *application) publishWebhooks(ctx context.Context, outboxEvent *data.OutboxEvent) error {
+	subscribers, err := app.models.Webhooks.GetAll(ctx, outboxEvent.Type)
+	if err != nil || len(subscribers) == 0 {
+		return err
+	}
+
+	event := &webhooks.Event{
+		ID:        outboxEvent.ID,
+		Type:      outboxEvent.Type,
+		CreatedAt: outboxEvent.CreatedAt.UTC(),
+		Data:      outboxEvent.Data,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range subscribers {
+		payload := webhookDeliveryPayload{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			Event:     event.Type,
+			Body:      body,
+		}
+
+		_, err := app.enqueue(ctx, jobWebhookDelivery, payload, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (app *application) deliverWebhook(ctx context.Context, job *data.Job) error {
+	var payload webhookDeliveryPayload
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := app.models.Webhooks.GetByID(ctx, payload.WebhookID)
+	if err != nil {
+		if errors.Is(err, data.ErrNoRecord) {
+			return nil
+		}
+		return err
+	}
+
+	if !webhook.Active {
+		return nil
+	}
+
+	result, deliveryErr := app.webhooks.Deliver(ctx, &webhooks.Delivery{
+		ID:      strconv.FormatInt(job.ID, 10),
+		URL:     webhook.URL,
+		Secret:  webhook.Secret,
+		EventID: payload.EventID,
+		Event:   payload.Event,
+		Body:    payload.Body,
+	})
+
+	delivery := &data.WebhookDelivery{
+		WebhookID: webhook.ID,
+		JobID:     &job.ID,
+		EventID:   payload.EventID,
+		Event:     payload.Event,
+		Payload:   payload.Body,
+		Attempt:   job.Attempts,
+		Succeeded: deliveryErr == nil,
+	}
+	if result != nil {
+		delivery.StatusCode = result.StatusCode
+		delivery.DurationMS = result.Duration.Milliseconds()
+	}
+	if deliveryErr != nil {
+		delivery.Error = deliveryErr.Error()
+	}
+
+	// the attempt is recorded even when it used up the job's time
+	err = app.models.Webhooks.InsertDelivery(context.WithoutCancel(ctx), delivery)
+	if err != nil {
+		app.logError(ctx, err, map[string]string{"webhook_id": strconv.FormatInt(webhook.ID, 10)})
+	}
+
+	return deliveryErr
+}
+
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := &data.Webhook{
+		URL:    input.URL,
+		Events: input.Events,
+		Active: true,
+	}
+
+	v := validator.NewValidator()
+	if data.ValidateWebhook(v, webhook, webhooks.ValidURL); !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	webhook.Secret, err = webhooks.NewSecret()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.models.Webhooks.InsertWebhook(r.Context(), webhook)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(
+		w, r, http.StatusCreated, envelope{
+			"message": "webhook created successfully, store the secret as it will not be shown again",
+			"webhook": webhook,
+			"secret":  webhook.Secret,
+		},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.models.Webhooks.GetAll(r.Context(), "")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"webhooks": webhooks})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Webhooks.DeleteByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "webhook deleted successfully"})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+	v := validator.NewValidator()
+
+	filter := data.Filter{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "-id"),
+		SafeSortList: []string{"id", "-id", "status_code", "-status_code"},
+	}
+
+	if data.ValidateFilters(v, &filter); !v.IsValid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Webhooks.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	deliveries, metadata, err := app.models.Webhooks.ListDeliveries(
+		r.Context(), id, filter,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(
+		w, r, http.StatusOK, envelope{"metadata": metadata, "deliveries": deliveries},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) replayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readParamID(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(
+		httprouter.ParamsFromContext(r.Context()).ByName("delivery_id"), 10, 64,
+	)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	delivery, err := app.models.Webhooks.GetDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	payload := webhookDeliveryPayload{
+		WebhookID: delivery.WebhookID,
+		EventID:   delivery.EventID,
+		Event:     delivery.Event,
+		Body:      delivery.Payload,
+	}
+
+	job, err := app.enqueue(r.Context(), jobWebhookDelivery, payload, 0)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(
+		w, r, http.StatusAccepted, envelope{
+			"message": "webhook delivery scheduled for replay",
+			"job":     job,
+		},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// ErrBatchAborted is recorded against the operations of an atomic batch that were rolled back,
+// or never run, because another operation in it failed.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// MovieOperation is one step of a batch. Version, when set, is the version the movie is expected
+// to be at, and a movie at any other version fails the operation with ErrEditConflic. Apply is
+// called with the movie to create, or the current one to update, and may change it or return an
+// error to fail the operation before anything is written. Movie and Err hold the outcome.
+type MovieOperation struct {
+	Op      string
+	ID      int64
+	Version int32
+	Apply   func(movie *Movie) error
+	Movie   *Movie
+	Err     error
+}
+
+// ExecBatch runs ops in order. In an atomic batch they share a single transaction, which is
+// rolled back if any of them fails, otherwise each one commits or fails on its own. The error
+// returned is only for failures outside of the operations themselves, which record theirs in Err.
+func (model *MovieModel) ExecBatch(
+	ctx context.Context, ops []*MovieOperation, atomic bool,
+) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.ExecBatch")
+	defer func() { endSpan(span, err) }()
+
+	if !atomic {
+		for _, op := range ops {
+			op.Err = model.execOperation(ctx, op)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	failed := false
+	for _, op := range ops {
+		if failed {
+			op.Err = ErrBatchAborted
+			continue
+		}
+
+		op.Err = execOperation(ctx, tx, op)
+		failed = op.Err != nil
+	}
+
+	if !failed {
+		return tx.Commit()
+	}
+
+	for _, op := range ops {
+		if op.Err == nil {
+			op.Err = ErrBatchAborted
+			op.Movie = nil
+		}
+	}
+
+	return nil
+}
+
+func (model *MovieModel) execOperation(ctx context.Context, op *MovieOperation) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = execOperation(ctx, tx, op)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func execOperation(ctx context.Context, tx *sql.Tx, op *MovieOperation) error {
+	if op.Op == BatchCreate {
+		movie := &Movie{}
+		err := op.Apply(movie)
+		if err != nil {
+			return err
+		}
+
+		err = insertMovie(ctx, tx, movie)
+		if err != nil {
+			return err
+		}
+
+		op.Movie = movie
+		return nil
+	}
+
+	// the row is locked so that the version checked here is still the one written over
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM movies
+		WHERE id = $1
+		FOR UPDATE
+	`, movieColumns)
+
+	var movie Movie
+	err := tx.QueryRowContext(ctx, query, op.ID).Scan(movieColumns.dest(&movie)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNoRecord
+		default:
+			return err
+		}
+	}
+
+	if op.Version != 0 && op.Version != movie.Version {
+		return ErrEditConflic
+	}
+
+	switch op.Op {
+	case BatchUpdate:
+		err = op.Apply(&movie)
+		if err != nil {
+			return err
+		}
+
+		err = updateMovie(ctx, tx, &movie)
+		if err != nil {
+			return err
+		}
+
+		op.Movie = &movie
+		return nil
+	case BatchDelete:
+		return deleteMovie(ctx, tx, movie.ID, movie.Version)
+	default:
+		return fmt.Errorf("unknown batch operation: %s", op.Op)
+	}
+}
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BulkMaxMovies is the most movies a single bulk update or delete may touch.
+const BulkMaxMovies = 1000
+
+var ErrBulkLimitExceeded = errors.New("bulk limit exceeded")
+
+// CountMismatchError is returned when a bulk operation's filter matches a different number of
+// movies than the caller confirmed, which usually means the filter is not the one they meant.
+type CountMismatchError struct {
+	Expected int
+	Actual   int
+}
+
+func (e *CountMismatchError) Error() string {
+	return fmt.Sprintf("filter matches %d movies, not %d", e.Actual, e.Expected)
+}
+
+// BulkUpdate locks the movies matching q and calls fn on each, updating those it reports as
+// changed. expected must equal the number of matching movies unless it is negative, and a dry
+// run goes through the same steps as a plain read, without locking or writing anything. It
+// returns the changed movies.
+func (model *MovieModel) BulkUpdate(
+	ctx context.Context, q MovieQuery, expected int, dryRun bool,
+	fn func(movie *Movie) (bool, error),
+) (_ []*Movie, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.BulkUpdate")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	movies, err := selectMatchingMovies(ctx, tx, q, expected, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []*Movie{}
+	for _, movie := range movies {
+		ok, err := fn(movie)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if !dryRun {
+			err = updateMovie(ctx, tx, movie)
+			if err != nil {
+				return nil, err
+			}
+		}
+		changed = append(changed, movie)
+	}
+
+	if dryRun {
+		return changed, nil
+	}
+
+	return changed, tx.Commit()
+}
+
+// BulkDelete deletes the movies matching q, with expected and dryRun working as they do for
+// BulkUpdate, and returns their ids.
+func (model *MovieModel) BulkDelete(
+	ctx context.Context, q MovieQuery, expected int, dryRun bool,
+) (_ []int64, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.BulkDelete")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	movies, err := selectMatchingMovies(ctx, tx, q, expected, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+
+		if !dryRun {
+			err = deleteMovie(ctx, tx, movie.ID, movie.Version)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if dryRun {
+		return ids, nil
+	}
+
+	return ids, tx.Commit()
+}
+
+// selectMatchingMovies returns the movies matching q, locking them for the rest of tx when lock
+// is set. A dry run leaves them unlocked so that it never holds up the writers it is previewing.
+func selectMatchingMovies(
+	ctx context.Context, tx *sql.Tx, q MovieQuery, expected int, lock bool,
+) ([]*Movie, error) {
+	where, args := q.where()
+
+	forUpdate := ""
+	if lock {
+		forUpdate = "FOR UPDATE"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM movies
+		%s
+		ORDER BY id
+		LIMIT $%d
+		%s
+	`, movieColumns, where, len(args)+1, forUpdate)
+	args = append(args, BulkMaxMovies+1)
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movies []*Movie
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(movieColumns.dest(&movie)...)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(movies) > BulkMaxMovies {
+		return nil, ErrBulkLimitExceeded
+	}
+	if expected >= 0 && len(movies) != expected {
+		return nil, &CountMismatchError{Expected: expected, Actual: len(movies)}
+	}
+
+	return movies, nil
+}
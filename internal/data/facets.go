@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFacet = errors.New("invalid facet")
+
+var FacetsSafeList = []string{"genres", "year", "decade"}
+
+var facetQueries = map[string]string{
+	"genres": `
+		SELECT 'genres', genre, COUNT(*), ROW_NUMBER() OVER(ORDER BY COUNT(*) DESC, genre ASC)
+		FROM filtered, unnest(genres) AS genre
+		GROUP BY genre
+	`,
+	"year": `
+		SELECT 'year', year::text, COUNT(*), ROW_NUMBER() OVER(ORDER BY year ASC)
+		FROM filtered
+		GROUP BY year
+	`,
+	"decade": `
+		SELECT 'decade', (year / 10 * 10)::text, COUNT(*), ROW_NUMBER() OVER(ORDER BY year / 10 ASC)
+		FROM filtered
+		GROUP BY year / 10
+	`,
+}
+
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Facets map[string][]FacetBucket
+
+func (model *MovieModel) Facets(
+	ctx context.Context, q MovieQuery, facets []string,
+) (_ Facets, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.Facets")
+	defer func() { endSpan(span, err) }()
+
+	result := make(Facets, len(facets))
+	if len(facets) == 0 {
+		return result, nil
+	}
+
+	where, args := q.where()
+
+	selects := make([]string, 0, len(facets))
+	for _, facet := range facets {
+		facetQuery, ok := facetQueries[facet]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFacet, facet)
+		}
+		selects = append(selects, facetQuery)
+		result[facet] = []FacetBucket{}
+	}
+
+	query := fmt.Sprintf(`
+		WITH filtered AS (
+			SELECT genres, year FROM movies
+			%s
+		)
+		SELECT facet, value, count FROM (%s) AS buckets (facet, value, count, position)
+		ORDER BY facet, position
+	`, where, strings.Join(selects, "UNION ALL"))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet string
+		var bucket FacetBucket
+		err := rows.Scan(&facet, &bucket.Value, &bucket.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		result[facet] = append(result[facet], bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"math"
 	"slices"
 	"strings"
@@ -22,7 +23,30 @@ func ValidateFilters(v *validator.Validator, f *Filter) {
 	v.CheckAdd(f.PageSize > 0, "page", "must be postive integer")
 	v.CheckAdd(f.PageSize <= 100, "page", "cannot exceed 100")
 
-	v.CheckAdd(validator.ValueInList(f.Sort, f.SafeSortList...), "sort", "invalid")
+	keys := f.sortKeys()
+	v.CheckAdd(len(keys) <= 5, "sort", "cannot have more than 5 keys")
+
+	columns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		v.CheckAdd(
+			validator.ValueInList(key, f.SafeSortList...), "sort",
+			fmt.Sprintf("invalid sort key %q", key),
+		)
+		columns = append(columns, strings.TrimPrefix(key, "-"))
+	}
+	v.CheckAdd(
+		validator.ListUnique(columns...), "sort", "cannot sort by the same column more than once",
+	)
+}
+
+func ValidateFields(v *validator.Validator, fields []string, safeList []string) {
+	for _, field := range fields {
+		v.CheckAdd(
+			validator.ValueInList(field, safeList...), "fields",
+			fmt.Sprintf("must only contain %s", strings.Join(safeList, ", ")),
+		)
+	}
+	v.CheckAdd(validator.ListUnique(fields...), "fields", "cannot have duplicates")
 }
 
 func (f Filter) Limit() int {
@@ -33,18 +57,39 @@ func (f *Filter) Offset() int {
 	return (f.Page - 1) * f.PageSize
 }
 
-func (f Filter) SortColumn() string {
-	if slices.Contains(f.SafeSortList, f.Sort) {
-		return strings.TrimPrefix(f.Sort, "-")
+func (f Filter) sortKeys() []string {
+	keys := strings.Split(f.Sort, ",")
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
 	}
-	panic("invalid sort: " + f.Sort)
+	return keys
 }
 
-func (f Filter) SortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+func (f Filter) OrderBy() string {
+	keys := f.sortKeys()
+	clauses := make([]string, 0, len(keys)+1)
+	sortedByID := false
+
+	for _, key := range keys {
+		if !slices.Contains(f.SafeSortList, key) {
+			panic("invalid sort: " + key)
+		}
+
+		column := strings.TrimPrefix(key, "-")
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+		}
+
+		clauses = append(clauses, column+" "+direction)
+		sortedByID = sortedByID || column == "id"
 	}
-	return "ASC"
+
+	if !sortedByID {
+		clauses = append(clauses, "id ASC")
+	}
+
+	return strings.Join(clauses, ", ")
 }
 
 type Metadata struct {
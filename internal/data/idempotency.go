@@ -0,0 +1,190 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyKey records a request made with an Idempotency-Key header, and once it has
+// finished, the response to replay when the same key is sent again.
+type IdempotencyKey struct {
+	UserID          int64
+	Key             string
+	Fingerprint     string
+	Status          string
+	ResponseStatus  int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+type IdempotencyModel struct {
+	DB *sql.DB
+}
+
+// Claim records key as being processed and reports whether this caller got it. Only one of
+// several concurrent claims for a key can succeed. A key that has expired, or that has been
+// processing for longer than lockTimeout because the request handling it never finished, is
+// taken over as if it were new. When the claim fails, the existing record is returned instead.
+func (model *IdempotencyModel) Claim(
+	ctx context.Context, userID int64, key, fingerprint string, ttl, lockTimeout time.Duration,
+) (_ bool, _ *IdempotencyKey, err error) {
+	ctx, span := startSpan(ctx, "IdempotencyModel.Claim")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = 'processing', response_status = NULL,
+			response_headers = NULL, response_body = NULL, created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+			OR (
+				idempotency_keys.status = 'processing'
+				AND idempotency_keys.created_at < NOW() - make_interval(secs => $5)
+			)
+		RETURNING user_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var claimed int64
+	err = model.DB.QueryRowContext(
+		ctx, query, userID, key, fingerprint, ttl.Seconds(), lockTimeout.Seconds(),
+	).Scan(&claimed)
+	switch {
+	case err == nil:
+		return true, nil, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return false, nil, err
+	}
+
+	existing, err := model.Get(ctx, userID, key)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return false, existing, nil
+}
+
+func (model *IdempotencyModel) Get(
+	ctx context.Context, userID int64, key string,
+) (_ *IdempotencyKey, err error) {
+	ctx, span := startSpan(ctx, "IdempotencyModel.Get")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT user_id, key, fingerprint, status, COALESCE(response_status, 0),
+			response_headers, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var idempotencyKey IdempotencyKey
+	var headers []byte
+	err = model.DB.QueryRowContext(ctx, query, userID, key).Scan(
+		&idempotencyKey.UserID,
+		&idempotencyKey.Key,
+		&idempotencyKey.Fingerprint,
+		&idempotencyKey.Status,
+		&idempotencyKey.ResponseStatus,
+		&headers,
+		&idempotencyKey.ResponseBody,
+		&idempotencyKey.CreatedAt,
+		&idempotencyKey.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	if headers != nil {
+		err = json.Unmarshal(headers, &idempotencyKey.ResponseHeaders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &idempotencyKey, nil
+}
+
+// Complete stores the response for a claimed key so later requests with it are replayed.
+func (model *IdempotencyModel) Complete(
+	ctx context.Context, userID int64, key string, status int, headers http.Header, body []byte,
+) (err error) {
+	ctx, span := startSpan(ctx, "IdempotencyModel.Complete")
+	defer func() { endSpan(span, err) }()
+
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = $3, response_headers = $4, response_body = $5
+		WHERE user_id = $1 AND key = $2 AND status = 'processing'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err = model.DB.ExecContext(ctx, query, userID, key, status, headersJSON, body)
+	return err
+}
+
+// Release forgets a claimed key whose request failed, so the client can retry it.
+func (model *IdempotencyModel) Release(ctx context.Context, userID int64, key string) (err error) {
+	ctx, span := startSpan(ctx, "IdempotencyModel.Release")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND status = 'processing'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err = model.DB.ExecContext(ctx, query, userID, key)
+	return err
+}
+
+func (model *IdempotencyModel) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "IdempotencyModel.DeleteExpired")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE expires_at < NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := model.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,376 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobDead      = "dead"
+)
+
+var (
+	ErrJobNotRetryable = errors.New("job not retryable")
+	ErrJobLockLost     = errors.New("job lock lost")
+)
+
+var JobStatuses = []string{JobPending, JobRunning, JobCompleted, JobDead}
+
+type Job struct {
+	ID          int64           `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Status      string          `json:"status"`
+	Priority    int             `json:"priority"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	Traceparent string          `json:"-"`
+}
+
+type JobModel struct {
+	DB *sql.DB
+}
+
+const jobColumns = `
+	id, created_at, kind, payload, result, status, priority, attempts, max_attempts, run_at,
+	locked_at, completed_at, last_error, traceparent
+`
+
+func scanJob(row interface{ Scan(...any) error }, job *Job, extra ...any) error {
+	var payload, result []byte
+	err := row.Scan(append(extra,
+		&job.ID,
+		&job.CreatedAt,
+		&job.Kind,
+		&payload,
+		&result,
+		&job.Status,
+		&job.Priority,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedAt,
+		&job.CompletedAt,
+		&job.LastError,
+		&job.Traceparent,
+	)...)
+	if err != nil {
+		return err
+	}
+
+	job.Payload = payload
+	if result != nil {
+		job.Result = result
+	}
+
+	return nil
+}
+
+func (model *JobModel) Enqueue(ctx context.Context, job *Job) (err error) {
+	ctx, span := startSpan(ctx, "JobModel.Enqueue")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertJob(ctx, tx, job)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertJob enqueues job as part of tx, so that it is only ever run if the rest of tx commits.
+func insertJob(ctx context.Context, tx *sql.Tx, job *Job) error {
+	query := `
+		INSERT INTO jobs (kind, payload, priority, max_attempts, run_at, traceparent)
+		VALUES ($1, $2, $3, $4, COALESCE($5, NOW()), $6)
+		RETURNING id, created_at, status, run_at
+	`
+
+	var runAt *time.Time
+	if !job.RunAt.IsZero() {
+		runAt = &job.RunAt
+	}
+
+	args := []any{
+		job.Kind,
+		[]byte(job.Payload),
+		job.Priority,
+		job.MaxAttempts,
+		runAt,
+		job.Traceparent,
+	}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(
+		&job.ID,
+		&job.CreatedAt,
+		&job.Status,
+		&job.RunAt,
+	)
+}
+
+// Dequeue claims the most urgent job that is ready to run. Jobs left running by a worker that
+// has held them for longer than lockTimeout are assumed to be abandoned and are claimed again,
+// as long as they have attempts left.
+func (model *JobModel) Dequeue(ctx context.Context, lockTimeout time.Duration) (_ *Job, err error) {
+	ctx, span := startSpan(ctx, "JobModel.Dequeue")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		UPDATE jobs
+		SET status = 'running', locked_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE (status = 'pending' AND run_at <= NOW())
+			OR (
+				status = 'running' AND locked_at < NOW() - $1 * INTERVAL '1 second'
+				AND attempts < max_attempts
+			)
+			ORDER BY priority DESC, run_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s
+	`, jobColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var job Job
+	err = scanJob(model.DB.QueryRowContext(ctx, query, lockTimeout.Seconds()), &job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+// Complete records a successful attempt. It returns ErrJobLockLost when the job is no longer
+// held under the lock it was dequeued with, because its lock timed out and it was claimed again.
+func (model *JobModel) Complete(ctx context.Context, job *Job) (err error) {
+	ctx, span := startSpan(ctx, "JobModel.Complete")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		UPDATE jobs
+		SET status = 'completed', completed_at = NOW(), locked_at = NULL, result = $1,
+			last_error = ''
+		WHERE id = $2 AND status = 'running' AND locked_at = $3
+		RETURNING status, completed_at
+	`
+
+	var result []byte
+	if job.Result != nil {
+		result = job.Result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = model.DB.QueryRowContext(ctx, query, result, job.ID, job.LockedAt).Scan(
+		&job.Status,
+		&job.CompletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobLockLost
+	}
+
+	return err
+}
+
+// Fail records a failed attempt. The job is scheduled to run again at retryAt unless it has
+// used up its attempts, in which case it is moved to the dead letter status. Like Complete, it
+// returns ErrJobLockLost when the job has since been claimed again.
+func (model *JobModel) Fail(
+	ctx context.Context, job *Job, jobErr error, retryAt time.Time,
+) (err error) {
+	ctx, span := startSpan(ctx, "JobModel.Fail")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+			run_at = $1, locked_at = NULL, last_error = $2
+		WHERE id = $3 AND status = 'running' AND locked_at = $4
+		RETURNING status, run_at, last_error
+	`
+
+	args := []any{retryAt, jobErr.Error(), job.ID, job.LockedAt}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = model.DB.QueryRowContext(ctx, query, args...).Scan(
+		&job.Status,
+		&job.RunAt,
+		&job.LastError,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobLockLost
+	}
+
+	return err
+}
+
+// BuryExpired moves the jobs whose lock timed out on their last attempt to the dead letter
+// status, since Dequeue will not claim them again.
+func (model *JobModel) BuryExpired(
+	ctx context.Context, lockTimeout time.Duration,
+) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "JobModel.BuryExpired")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		UPDATE jobs
+		SET status = 'dead', locked_at = NULL, last_error = 'lock timed out on the last attempt'
+		WHERE status = 'running' AND locked_at < NOW() - $1 * INTERVAL '1 second'
+		AND attempts >= max_attempts
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := model.DB.ExecContext(ctx, query, lockTimeout.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (model *JobModel) Retry(ctx context.Context, id int64) (_ *Job, err error) {
+	ctx, span := startSpan(ctx, "JobModel.Retry")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = NOW(), locked_at = NULL
+		WHERE id = $1 AND status IN ('pending', 'dead')
+		RETURNING %s
+	`, jobColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var job Job
+	err = scanJob(model.DB.QueryRowContext(ctx, query, id), &job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err := model.GetByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrJobNotRetryable
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+func (model *JobModel) GetByID(ctx context.Context, id int64) (_ *Job, err error) {
+	ctx, span := startSpan(ctx, "JobModel.GetByID")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM jobs
+		WHERE id = $1
+	`, jobColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var job Job
+	err = scanJob(model.DB.QueryRowContext(ctx, query, id), &job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+func (model *JobModel) ListJobs(
+	ctx context.Context, status, kind string, filter Filter,
+) (_ []*Job, _ *Metadata, err error) {
+	ctx, span := startSpan(ctx, "JobModel.ListJobs")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM jobs
+		WHERE (status = $1 OR $1 = '')
+		AND (kind = $2 OR $2 = '')
+		ORDER BY %s
+		LIMIT $3
+		OFFSET $4
+	`, jobColumns, filter.OrderBy())
+	args := []any{
+		status,
+		kind,
+		filter.Limit(),
+		filter.Offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	totalRecords := 0
+	for rows.Next() {
+		var job Job
+		err := scanJob(rows, &job, &totalRecords)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		jobs = append(jobs, &job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := NewMetadata(filter.Page, filter.PageSize, totalRecords)
+
+	return jobs, metadata, nil
+}
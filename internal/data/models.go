@@ -7,6 +7,10 @@ type Models struct {
 	Users       *UserModel
 	Tokens      *TokenModel
 	Permissions *PermissionModel
+	Jobs        *JobModel
+	Webhooks    *WebhookModel
+	Outbox      *OutboxModel
+	Idempotency *IdempotencyModel
 }
 
 func NewModels(db *sql.DB) *Models {
@@ -15,5 +19,9 @@ func NewModels(db *sql.DB) *Models {
 		Users:       &UserModel{DB: db},
 		Tokens:      &TokenModel{DB: db},
 		Permissions: &PermissionModel{DB: db},
+		Jobs:        &JobModel{DB: db},
+		Webhooks:    &WebhookModel{DB: db},
+		Outbox:      &OutboxModel{DB: db},
+		Idempotency: &IdempotencyModel{DB: db},
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Yusufdot101/greenlight/internal/validator"
@@ -16,6 +18,8 @@ var (
 	ErrEditConflic = errors.New("edit conflict")
 )
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type Movie struct {
 	ID        int64     `json:"id"`
 	CreatedAt time.Time `json:"-"`
@@ -42,11 +46,82 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.CheckAdd(validator.ListUnique(movie.Genres...), "genres", "cannot have duplicates")
 }
 
+var MovieFieldsSafeList = []string{"id", "title", "runtime", "year", "genres", "version"}
+
+type movieColumn struct {
+	name string
+	dest func(movie *Movie) any
+}
+
+var movieColumns = movieColumnList{
+	{"id", func(movie *Movie) any { return &movie.ID }},
+	{"created_at", func(movie *Movie) any { return &movie.CreatedAt }},
+	{"title", func(movie *Movie) any { return &movie.Title }},
+	{"runtime", func(movie *Movie) any { return &movie.Runtime }},
+	{"year", func(movie *Movie) any { return &movie.Year }},
+	{"genres", func(movie *Movie) any { return pq.Array(&movie.Genres) }},
+	{"version", func(movie *Movie) any { return &movie.Version }},
+}
+
+type movieColumnList []movieColumn
+
+func selectMovieColumns(fields []string) movieColumnList {
+	if len(fields) == 0 {
+		return movieColumns
+	}
+
+	columns := make(movieColumnList, 0, len(fields))
+	for _, column := range movieColumns {
+		if slices.Contains(fields, column.name) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+func (columns movieColumnList) String() string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (columns movieColumnList) dest(movie *Movie) []any {
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		dest[i] = column.dest(movie)
+	}
+	return dest
+}
+
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func (model *MovieModel) InsertMovie(movie *Movie) error {
+func (model *MovieModel) InsertMovie(ctx context.Context, movie *Movie) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.InsertMovie")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertMovie(ctx, tx, movie)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertMovie(ctx context.Context, tx *sql.Tx, movie *Movie) error {
 	query := `
 	INSERT INTO movies (title, runtime, year, genres)
 	VALUES ($1, $2, $3, $4)
@@ -59,10 +134,7 @@ func (model *MovieModel) InsertMovie(movie *Movie) error {
 		pq.Array(movie.Genres),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := model.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&movie.ID,
 		&movie.CreatedAt,
 		&movie.Version,
@@ -71,29 +143,29 @@ func (model *MovieModel) InsertMovie(movie *Movie) error {
 		return err
 	}
 
-	return nil
+	return insertOutboxEvent(
+		ctx, tx, EventMovieCreated, "movie", movie.ID, map[string]any{"movie": movie},
+	)
 }
 
-func (model *MovieModel) GetByID(id int64) (*Movie, error) {
-	query := `
-		SELECT id, created_at, title, runtime, year, genres, version
+func (model *MovieModel) GetByID(
+	ctx context.Context, id int64, fields ...string,
+) (_ *Movie, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.GetByID")
+	defer func() { endSpan(span, err) }()
+
+	columns := selectMovieColumns(fields)
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM movies
 		WHERE id = $1
-	`
+	`, columns)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	var movie Movie
-	err := model.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Runtime,
-		&movie.Year,
-		pq.Array(&movie.Genres),
-		&movie.Version,
-	)
+	err = model.DB.QueryRowContext(ctx, query, id).Scan(columns.dest(&movie)...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -106,16 +178,51 @@ func (model *MovieModel) GetByID(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (model *MovieModel) DeleteByID(id int64) error {
+func (model *MovieModel) DeleteByID(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.DeleteByID")
+	defer func() { endSpan(span, err) }()
+
+	return model.deleteMovie(ctx, id, 0)
+}
+
+// DeleteVersion deletes the movie only if it is still at the given version, returning
+// ErrEditConflic when it has been changed or deleted since.
+func (model *MovieModel) DeleteVersion(ctx context.Context, id int64, version int32) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.DeleteVersion")
+	defer func() { endSpan(span, err) }()
+
+	err = model.deleteMovie(ctx, id, version)
+	if errors.Is(err, ErrNoRecord) {
+		return ErrEditConflic
+	}
+	return err
+}
+
+func (model *MovieModel) deleteMovie(ctx context.Context, id int64, version int32) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteMovie(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteMovie(ctx context.Context, tx *sql.Tx, id int64, version int32) error {
 	query := `
 		DELETE FROM movies
-		WHERE id = $1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	res, err := model.DB.ExecContext(ctx, query, id)
+	res, err := tx.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -129,10 +236,33 @@ func (model *MovieModel) DeleteByID(id int64) error {
 		return ErrNoRecord
 	}
 
-	return nil
+	return insertOutboxEvent(
+		ctx, tx, EventMovieDeleted, "movie", id, map[string]any{"movie": map[string]any{"id": id}},
+	)
+}
+
+func (model *MovieModel) UpdateMovie(ctx context.Context, movie *Movie) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.UpdateMovie")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateMovie(ctx, tx, movie)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (model *MovieModel) UpdateMovie(movie *Movie) error {
+func updateMovie(ctx context.Context, tx *sql.Tx, movie *Movie) error {
 	query := `
 		UPDATE movies
 		SET title = $1, runtime = $2, year = $3, genres = $4, version = version + 1
@@ -148,10 +278,7 @@ func (model *MovieModel) UpdateMovie(movie *Movie) error {
 		movie.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := model.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -161,30 +288,138 @@ func (model *MovieModel) UpdateMovie(movie *Movie) error {
 		}
 	}
 
-	return nil
+	return insertOutboxEvent(
+		ctx, tx, EventMovieUpdated, "movie", movie.ID, map[string]any{"movie": movie},
+	)
 }
 
-func (model *MovieModel) ListMovies(
-	title string, year int, genres []string, filter Filter,
-) ([]*Movie, *Metadata, error) {
-	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, created_at, title, runtime, year, genres, version FROM movies
-		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (year = $2 OR $2 = -1)
-		AND (genres @> $3 OR $3 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $4
-		OFFSET $5
-	`, filter.SortColumn(), filter.SortDirection())
+type MovieQuery struct {
+	Title         string
+	Fuzzy         bool
+	Year          int
+	YearMin       int
+	YearMax       int
+	RuntimeMin    int
+	RuntimeMax    int
+	Genres        []string
+	GenresAny     []string
+	ExcludeGenres []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+func ValidateMovieQuery(v *validator.Validator, q *MovieQuery) {
+	currentYear := time.Now().Year()
+	yearMessage := fmt.Sprintf("must be between 1888 and %d", currentYear)
+
+	v.CheckAdd(q.Year == 0 || q.Year >= 1888 && q.Year <= currentYear, "year", yearMessage)
+	v.CheckAdd(
+		q.YearMin == 0 || q.YearMin >= 1888 && q.YearMin <= currentYear, "year_min", yearMessage,
+	)
+	v.CheckAdd(
+		q.YearMax == 0 || q.YearMax >= 1888 && q.YearMax <= currentYear, "year_max", yearMessage,
+	)
+	v.CheckAdd(
+		q.YearMin == 0 || q.YearMax == 0 || q.YearMin <= q.YearMax, "year_max",
+		"must be greater than or equal to year_min",
+	)
+
+	v.CheckAdd(q.RuntimeMin >= 0, "runtime_min", "must be a postive number of minutes")
+	v.CheckAdd(q.RuntimeMax >= 0, "runtime_max", "must be a postive number of minutes")
+	v.CheckAdd(
+		q.RuntimeMin == 0 || q.RuntimeMax == 0 || q.RuntimeMin <= q.RuntimeMax, "runtime_max",
+		"must be greater than or equal to runtime_min",
+	)
+
+	validateGenreList(v, "genres", q.Genres)
+	validateGenreList(v, "genres_any", q.GenresAny)
+	validateGenreList(v, "exclude_genres", q.ExcludeGenres)
+	for _, genre := range q.ExcludeGenres {
+		v.CheckAdd(
+			!validator.ValueInList(genre, q.Genres...), "exclude_genres",
+			"cannot contain a genre that is also required by genres",
+		)
+	}
+
+	v.CheckAdd(
+		q.CreatedAfter == nil || q.CreatedBefore == nil || q.CreatedAfter.Before(*q.CreatedBefore),
+		"created_before", "must be later than created_after",
+	)
+}
+
+func validateGenreList(v *validator.Validator, key string, genres []string) {
+	v.CheckAdd(len(genres) <= 20, key, "cannot contain more than 20 genres")
+	v.CheckAdd(validator.ListUnique(genres...), key, "cannot have duplicates")
+	v.CheckAdd(!validator.ValueInList("", genres...), key, "cannot contain empty genres")
+}
+
+func (q MovieQuery) where() (string, []any) {
+	titleCondition := "to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)"
+	if q.Fuzzy {
+		titleCondition = "title % $1"
+	}
+
+	where := fmt.Sprintf(`
+		WHERE (%s OR $1 = '')
+		AND (year = $2 OR $2 = 0)
+		AND (year >= $3 OR $3 = 0)
+		AND (year <= $4 OR $4 = 0)
+		AND (runtime >= $5 OR $5 = 0)
+		AND (runtime <= $6 OR $6 = 0)
+		AND (genres @> $7 OR $7 = '{}')
+		AND (genres && $8 OR $8 = '{}')
+		AND (NOT genres && $9 OR $9 = '{}')
+		AND (created_at > $10 OR $10 IS NULL)
+		AND (created_at < $11 OR $11 IS NULL)
+	`, titleCondition)
 	args := []any{
-		title,
-		year,
-		pq.Array(genres),
-		filter.Limit(),
-		filter.Offset(),
+		q.Title,
+		q.Year,
+		q.YearMin,
+		q.YearMax,
+		q.RuntimeMin,
+		q.RuntimeMax,
+		textArray(q.Genres),
+		textArray(q.GenresAny),
+		textArray(q.ExcludeGenres),
+		q.CreatedAfter,
+		q.CreatedBefore,
+	}
+
+	return where, args
+}
+
+func textArray(values []string) any {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+func (model *MovieModel) ListMovies(
+	ctx context.Context, q MovieQuery, filter Filter, fields ...string,
+) (_ []*Movie, _ *Metadata, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.ListMovies")
+	defer func() { endSpan(span, err) }()
+
+	columns := selectMovieColumns(fields)
+	where, args := q.where()
+
+	orderBy := filter.OrderBy()
+	if q.Fuzzy {
+		orderBy = "similarity(title, $1) DESC, " + orderBy
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s FROM movies
+		%s
+		ORDER BY %s
+		LIMIT $%d
+		OFFSET $%d
+	`, columns, where, orderBy, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit(), filter.Offset())
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := model.DB.QueryContext(ctx, query, args...)
@@ -195,10 +430,390 @@ func (model *MovieModel) ListMovies(
 
 	var movies []*Movie
 	totalRecords := 0
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(append([]any{&totalRecords}, columns.dest(&movie)...)...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := NewMetadata(filter.Page, filter.PageSize, totalRecords)
+
+	return movies, metadata, nil
+}
+
+func (model *MovieModel) StreamMovies(
+	ctx context.Context, q MovieQuery, filter Filter, fn func(movie *Movie) error,
+) (err error) {
+	ctx, span := startSpan(ctx, "MovieModel.StreamMovies")
+	defer func() { endSpan(span, err) }()
+
+	where, args := q.where()
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM movies
+		%s
+		ORDER BY %s
+	`, movieColumns, where, filter.OrderBy())
+
+	rows, err := model.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(movieColumns.dest(&movie)...)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&movie)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+type TitleMatch struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+func (model *MovieModel) Autocomplete(
+	ctx context.Context, prefix string, limit int,
+) (_ []*TitleMatch, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.Autocomplete")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT id, title FROM movies
+		WHERE title ILIKE $2 OR title % $1
+		ORDER BY title ILIKE $2 DESC, similarity(title, $1) DESC, id ASC
+		LIMIT $3
+	`
+	args := []any{
+		prefix,
+		likeEscaper.Replace(prefix) + "%",
+		limit,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []*TitleMatch{}
+	for rows.Next() {
+		var match TitleMatch
+		err := rows.Scan(&match.ID, &match.Title)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, &match)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+func (model *MovieModel) SuggestTitle(ctx context.Context, title string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.SuggestTitle")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT title FROM movies
+		WHERE title % $1
+		ORDER BY similarity(title, $1) DESC, id ASC
+		LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var suggestion string
+	err = model.DB.QueryRowContext(ctx, query, title).Scan(&suggestion)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrNoRecord
+		default:
+			return "", err
+		}
+	}
+
+	return suggestion, nil
+}
+
+const (
+	MaxImportRows   = 10_000
+	importBatchSize = 500
+)
+
+var (
+	ErrImportTooLarge = errors.New("import too large")
+
+	// ErrImportAborted is recorded against the movies of an atomic import that were not
+	// imported because another movie in it was rejected.
+	ErrImportAborted = errors.New("import aborted")
+)
+
+// MovieRejectedError is returned for a movie the database refused because of its own values,
+// such as one that breaks a constraint, as opposed to the database failing. Errors describes
+// what is wrong with it by field.
+type MovieRejectedError struct {
+	Errors map[string]string
+	Err    error
+}
+
+func (e *MovieRejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MovieRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// rejectedMovie turns an error caused by the values of a movie into a MovieRejectedError, and
+// returns any other error as it is.
+func rejectedMovie(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	errs := make(map[string]string)
+	switch {
+	case pqErr.Constraint == "movies_runtime_check":
+		errs["runtime"] = "must be postive integer"
+	case pqErr.Constraint == "movies_year_check":
+		errs["year"] = fmt.Sprintf("must be between 1888 and %d", time.Now().Year())
+	case pqErr.Constraint == "genres_length_check":
+		errs["genres"] = "must have between one and five genres"
+	case pqErr.Code.Name() == "not_null_violation" && pqErr.Column != "":
+		errs[pqErr.Column] = "must be provided"
+	case pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23":
+		errs["row"] = pqErr.Message
+	default:
+		return err
+	}
+
+	return &MovieRejectedError{Errors: errs, Err: err}
+}
+
+// ImportMovies inserts movies in batches of importBatchSize. In atomic mode every batch is
+// written in the same transaction, so either all of them are imported or none are. Otherwise
+// each batch commits on its own, and the movies of a batch that fails are retried one by one.
+// The returned map holds, by index, the errors of the movies that were not imported: a
+// MovieRejectedError for those the database refused and, in atomic mode, ErrImportAborted for
+// the rest.
+func (model *MovieModel) ImportMovies(
+	ctx context.Context, movies []*Movie, atomic bool,
+) (_ map[int]error, err error) {
+	ctx, span := startSpan(ctx, "MovieModel.ImportMovies")
+	defer func() { endSpan(span, err) }()
+
+	if len(movies) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+
+	failed := make(map[int]error)
+
+	if atomic {
+		err = model.importAtomic(ctx, movies)
+		if err == nil {
+			return failed, nil
+		}
+
+		var rejected *MovieRejectedError
+		if !errors.As(rejectedMovie(err), &rejected) {
+			return nil, err
+		}
+
+		// COPY does not say which movie was rejected, so find it by inserting them one by one
+		i, reason, err := model.findRejectedMovie(ctx, movies)
+		if err != nil {
+			return nil, err
+		}
+		if reason == nil {
+			return nil, rejected
+		}
+
+		for j := range movies {
+			failed[j] = ErrImportAborted
+		}
+		failed[i] = reason
+		return failed, nil
+	}
+
+	for start := 0; start < len(movies); start += importBatchSize {
+		batch := movies[start:min(start+importBatchSize, len(movies))]
+
+		err := model.importBatch(ctx, batch)
+		if err == nil {
+			continue
+		}
+
+		for i, movie := range batch {
+			err := model.InsertMovie(ctx, movie)
+			if err != nil {
+				failed[start+i] = rejectedMovie(err)
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+func (model *MovieModel) importAtomic(ctx context.Context, movies []*Movie) error {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(movies); start += importBatchSize {
+		err = copyMovies(ctx, tx, movies[start:min(start+importBatchSize, len(movies))])
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// findRejectedMovie inserts movies one by one in a transaction that is always rolled back, and
+// returns the index of the first one the database rejects along with the reason, which is nil
+// if none of them is.
+func (model *MovieModel) findRejectedMovie(
+	ctx context.Context, movies []*Movie,
+) (int, *MovieRejectedError, error) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	for i, movie := range movies {
+		err = insertMovie(ctx, tx, movie)
+		if err == nil {
+			continue
+		}
+
+		var rejected *MovieRejectedError
+		if !errors.As(rejectedMovie(err), &rejected) {
+			return 0, nil, err
+		}
+		return i, rejected, nil
+	}
+
+	return 0, nil, nil
+}
+
+func (model *MovieModel) importBatch(ctx context.Context, batch []*Movie) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = copyMovies(ctx, tx, batch)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// copyMovies streams movies into a temporary staging table with COPY and moves them into movies
+// from there, so that the inserted rows come back and each gets its movie.created outbox event
+// in the same transaction, just as if it had been inserted by insertMovie.
+func copyMovies(ctx context.Context, tx *sql.Tx, movies []*Movie) error {
+	query := `
+		CREATE TEMPORARY TABLE IF NOT EXISTS movie_import (
+			position integer NOT NULL,
+			title text NOT NULL,
+			runtime integer NOT NULL,
+			year integer NOT NULL,
+			genres text[] NOT NULL
+		) ON COMMIT DROP
+	`
+	_, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "TRUNCATE movie_import")
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx, pq.CopyIn("movie_import", "position", "title", "runtime", "year", "genres"),
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, movie := range movies {
+		_, err = stmt.ExecContext(
+			ctx, i, movie.Title, movie.Runtime, movie.Year, pq.Array(movie.Genres),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO movies (title, runtime, year, genres)
+		SELECT title, runtime, year, genres FROM movie_import
+		ORDER BY position
+		RETURNING id, created_at, title, runtime, year, genres, version
+	`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inserted := make([]*Movie, 0, len(movies))
 	for rows.Next() {
 		var movie Movie
 		err := rows.Scan(
-			&totalRecords,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.Title,
@@ -208,17 +823,25 @@ func (model *MovieModel) ListMovies(
 			&movie.Version,
 		)
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 
-		movies = append(movies, &movie)
+		inserted = append(inserted, &movie)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, nil, err
+		return err
 	}
+	rows.Close()
 
-	metadata := NewMetadata(filter.Page, filter.PageSize, totalRecords)
+	for _, movie := range inserted {
+		err = insertOutboxEvent(
+			ctx, tx, EventMovieCreated, "movie", movie.ID, map[string]any{"movie": movie},
+		)
+		if err != nil {
+			return err
+		}
+	}
 
-	return movies, metadata, nil
+	return nil
 }
@@ -0,0 +1,315 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OutboxEvent struct {
+	Sequence    int64           `json:"sequence"`
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Aggregate   string          `json:"aggregate"`
+	AggregateID int64           `json:"aggregate_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Data        json.RawMessage `json:"data"`
+
+	// PublishedSequence is assigned by the relay when the event is published. Unlike Sequence,
+	// which follows insert order, it follows commit order, so it is safe to resume from.
+	PublishedSequence int64 `json:"published_sequence,omitempty"`
+}
+
+// insertOutboxEvent records an event in the same transaction as the write it describes, so the
+// event exists if and only if the write was committed.
+func insertOutboxEvent(
+	ctx context.Context, tx *sql.Tx, eventType, aggregate string, aggregateID int64, eventData any,
+) error {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(eventData)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox (event_id, event, aggregate, aggregate_id, payload)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	args := []any{
+		hex.EncodeToString(randomBytes),
+		eventType,
+		aggregate,
+		aggregateID,
+		payload,
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+const outboxColumns = `id, event_id, event, aggregate, aggregate_id, created_at, payload,
+	published_seq`
+
+func scanOutboxEvents(rows *sql.Rows) ([]*OutboxEvent, error) {
+	defer rows.Close()
+
+	events := []*OutboxEvent{}
+	for rows.Next() {
+		var event OutboxEvent
+		var payload []byte
+		var publishedSequence sql.NullInt64
+		err := rows.Scan(
+			&event.Sequence,
+			&event.ID,
+			&event.Type,
+			&event.Aggregate,
+			&event.AggregateID,
+			&event.CreatedAt,
+			&payload,
+			&publishedSequence,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Data = payload
+		event.PublishedSequence = publishedSequence.Int64
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// outboxRelayLock is the advisory lock key that serializes relays.
+const outboxRelayLock = 7401
+
+type OutboxModel struct {
+	DB *sql.DB
+}
+
+// Relay hands up to limit unpublished events to fn one at a time, in the order they were
+// written. It stops at the first event fn rejects so that later events are never published
+// ahead of it, and marks everything before that point as published. Only one relay runs at a
+// time: the others return straight away without publishing anything, and the events are left
+// to the relay holding the lock, which keeps the order intact.
+//
+// No transaction is held open while fn runs, since fn usually calls out over the network.
+// The events are read first and only marked as published once fn has accepted them, so an
+// event is published again if the relay dies in between.
+//
+// Each published event is given the next published_seq. Because relays run one at a time, that
+// sequence grows in commit order even though ids, taken at insert time, do not: a transaction
+// that inserted a lower id can commit after one that inserted a higher id.
+func (model *OutboxModel) Relay(
+	ctx context.Context, limit int, fn func(*OutboxEvent) error,
+) (_ int, err error) {
+	ctx, span := startSpan(ctx, "OutboxModel.Relay")
+	defer func() { endSpan(span, err) }()
+
+	// the lock is held by the session rather than a transaction, so it needs a connection of
+	// its own for as long as the relay runs
+	conn, err := model.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	locked, err := lockOutboxRelay(ctx, conn)
+	if err != nil || !locked {
+		return 0, err
+	}
+	defer unlockOutboxRelay(conn)
+
+	events, err := unpublishedOutboxEvents(ctx, conn, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var publishErr error
+	published := events
+	for i, event := range events {
+		publishErr = fn(event)
+		if publishErr != nil {
+			published = events[:i]
+			break
+		}
+	}
+
+	err = markOutboxEventsPublished(ctx, conn, published)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(published), publishErr
+}
+
+func lockOutboxRelay(ctx context.Context, conn *sql.Conn) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var locked bool
+	err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, outboxRelayLock).
+		Scan(&locked)
+	return locked, err
+}
+
+// unlockOutboxRelay releases the relay lock. If that fails, the connection is thrown away
+// rather than going back to the pool, which releases the lock when the session ends.
+func unlockOutboxRelay(conn *sql.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, outboxRelayLock)
+	if err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+func unpublishedOutboxEvents(
+	ctx context.Context, conn *sql.Conn, limit int,
+) ([]*OutboxEvent, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id ASC
+		LIMIT $1
+	`, outboxColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxEvents(rows)
+}
+
+// markOutboxEventsPublished marks events as published in a single short transaction, one at a
+// time so that published_seq is handed out in the order they were published.
+func markOutboxEventsPublished(
+	ctx context.Context, conn *sql.Conn, events []*OutboxEvent,
+) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE outbox
+		SET published_at = NOW(), published_seq = nextval('outbox_published_seq')
+		WHERE id = $1
+		RETURNING published_seq
+	`
+
+	// the events have already been published, so they are marked even if the relay is being
+	// shut down
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, event := range events {
+		err = tx.QueryRowContext(ctx, query, event.Sequence).Scan(&event.PublishedSequence)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// EventsAfter returns the published events whose published sequence is greater than sequence.
+// Events the relay has not reached yet are left out; they are given a higher sequence when it
+// does, so a reader resuming from the last sequence it saw never skips one.
+func (model *OutboxModel) EventsAfter(
+	ctx context.Context, aggregate string, sequence int64, limit int,
+) (_ []*OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "OutboxModel.EventsAfter")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM outbox
+		WHERE (aggregate = $1 OR $1 = '') AND published_seq > $2
+		ORDER BY published_seq ASC
+		LIMIT $3
+	`, outboxColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, aggregate, sequence, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxEvents(rows)
+}
+
+func (model *OutboxModel) GetByPublishedSequence(
+	ctx context.Context, sequences []int64,
+) (_ []*OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "OutboxModel.GetByPublishedSequence")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM outbox
+		WHERE published_seq = ANY($1)
+		ORDER BY published_seq ASC
+	`, outboxColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, pq.Array(sequences))
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxEvents(rows)
+}
+
+func (model *OutboxModel) DeletePublished(
+	ctx context.Context, before time.Time,
+) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "OutboxModel.DeletePublished")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		DELETE FROM outbox
+		WHERE published_at < $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := model.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
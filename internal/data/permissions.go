@@ -20,7 +20,12 @@ type PermissionModel struct {
 	DB *sql.DB
 }
 
-func (model *PermissionModel) GellAllForUser(userID int64) (Permissions, error) {
+func (model *PermissionModel) GellAllForUser(
+	ctx context.Context, userID int64,
+) (_ Permissions, err error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GellAllForUser")
+	defer func() { endSpan(span, err) }()
+
 	query := `
 		SELECT permissions.code
 		FROM permissions
@@ -29,7 +34,7 @@ func (model *PermissionModel) GellAllForUser(userID int64) (Permissions, error)
 		WHERE users.id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := model.DB.QueryContext(ctx, query, userID)
@@ -61,15 +66,35 @@ func (model *PermissionModel) GellAllForUser(userID int64) (Permissions, error)
 	return permissions, nil
 }
 
-func (model *PermissionModel) AddForUser(useID int64, code ...string) error {
+func (model *PermissionModel) AddForUser(
+	ctx context.Context, useID int64, code ...string,
+) (err error) {
+	ctx, span := startSpan(ctx, "PermissionModel.AddForUser")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = addPermissionsForUser(ctx, tx, useID, code...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func addPermissionsForUser(ctx context.Context, tx *sql.Tx, userID int64, code ...string) error {
 	query := `
 		INSERT INTO users_permissions
 		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := model.DB.ExecContext(ctx, query, useID, pq.Array(code))
+	_, err := tx.ExecContext(ctx, query, userID, pq.Array(code))
 	return err
 }
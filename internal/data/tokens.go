@@ -56,19 +56,22 @@ type TokenModel struct {
 }
 
 func (model TokenModel) NewToken(
-	userID int64, timeToLive time.Duration, scope string) (*Token, error,
+	ctx context.Context, userID int64, timeToLive time.Duration, scope string) (*Token, error,
 ) {
 	token, err := generateToken(userID, timeToLive, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	err = model.InsertToken(token)
+	err = model.InsertToken(ctx, token)
 
 	return token, err
 }
 
-func (model *TokenModel) InsertToken(token *Token) error {
+func (model *TokenModel) InsertToken(ctx context.Context, token *Token) (err error) {
+	ctx, span := startSpan(ctx, "TokenModel.InsertToken")
+	defer func() { endSpan(span, err) }()
+
 	query := `
 		INSERT INTO tokens (hash, user_id, expiry, scope)
 		VALUES ($1, $2, $3, $4)
@@ -80,24 +83,29 @@ func (model *TokenModel) InsertToken(token *Token) error {
 		token.Scope,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	_, err := model.DB.ExecContext(ctx, query, args...)
+	_, err = model.DB.ExecContext(ctx, query, args...)
 
 	return err
 }
 
-func (model *TokenModel) DeleteAllForUser(userID int64, scope string) error {
+func (model *TokenModel) DeleteAllForUser(
+	ctx context.Context, userID int64, scope string,
+) (err error) {
+	ctx, span := startSpan(ctx, "TokenModel.DeleteAllForUser")
+	defer func() { endSpan(span, err) }()
+
 	query := `
 		DELETE FROM tokens
 		WHERE user_id = $1 AND scope = $2
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	_, err := model.DB.ExecContext(ctx, query, userID, scope)
+	_, err = model.DB.ExecContext(ctx, query, userID, scope)
 
 	return err
 }
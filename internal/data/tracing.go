@@ -0,0 +1,30 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+)
+
+// startSpan starts a span around a model method that queries the database, named after it
+// like MovieModel.GetByID.
+func startSpan(ctx context.Context, method string) (context.Context, *tracing.Span) {
+	return tracing.Start(
+		ctx, method, tracing.SpanKindClient, tracing.String("db.system.name", "postgresql"),
+	)
+}
+
+// endSpan ends a span started by startSpan, marking it as failed unless err is nil or one of the
+// errors the models return for expected outcomes such as a missing record.
+func endSpan(span *tracing.Span, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoRecord), errors.Is(err, ErrEditConflic),
+		errors.Is(err, ErrDuplicateEmail):
+		span.SetAttributes(tracing.String("greenlight.outcome", err.Error()))
+	default:
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -26,6 +26,7 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
+	Locale    string    `json:"locale,omitempty"`
 	Version   int32     `json:"version"`
 }
 
@@ -75,6 +76,10 @@ func ValidateUser(v *validator.Validator, user *User) {
 	v.CheckAdd(len(user.Name) <= 500, "name", "cannot be more than 500 characters")
 
 	ValidateEmail(v, user.Email)
+	v.CheckAdd(
+		user.Locale == "" || validator.Matches(user.Locale, validator.LocaleRX), "locale",
+		"must be a language tag such as en or pt-br",
+	)
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
 	}
@@ -88,10 +93,72 @@ type UserModel struct {
 	DB *sql.DB
 }
 
-func (model *UserModel) InsertUser(user *User) error {
+func (model *UserModel) InsertUser(ctx context.Context, user *User) (err error) {
+	ctx, span := startSpan(ctx, "UserModel.InsertUser")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Register inserts a new user together with their permissions and the job that welcomes them,
+// which newJob builds once the user has an ID. They are committed together, so a user is never
+// left without their welcome email, and a failed registration can simply be retried.
+func (model *UserModel) Register(
+	ctx context.Context, user *User, permissions []string, newJob func(user *User) (*Job, error),
+) (err error) {
+	ctx, span := startSpan(ctx, "UserModel.Register")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := model.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = addPermissionsForUser(ctx, tx, user.ID, permissions...)
+	if err != nil {
+		return err
+	}
+
+	job, err := newJob(user)
+	if err != nil {
+		return err
+	}
+
+	err = insertJob(ctx, tx, job)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertUser(ctx context.Context, tx *sql.Tx, user *User) error {
 	query := `
-		INSERT INTO users (name, email, password_hash, activated)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (name, email, password_hash, activated, locale)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, version
 	`
 	args := []any{
@@ -99,12 +166,10 @@ func (model *UserModel) InsertUser(user *User) error {
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.Locale,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := model.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Version,
@@ -121,24 +186,64 @@ func (model *UserModel) InsertUser(user *User) error {
 	return nil
 }
 
-func (model *UserModel) GetUserByEmail(email string) (*User, error) {
+func (model *UserModel) GetUserByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, span := startSpan(ctx, "UserModel.GetUserByEmail")
+	defer func() { endSpan(span, err) }()
+
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version 
+		SELECT id, created_at, name, email, password_hash, activated, locale, version
 		FROM users
 		WHERE email = $1
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var user User
+	err = model.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Locale,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (model *UserModel) GetUserByID(ctx context.Context, id int64) (_ *User, err error) {
+	ctx, span := startSpan(ctx, "UserModel.GetUserByID")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, locale, version
+		FROM users
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	var user User
-	err := model.DB.QueryRowContext(ctx, query, email).Scan(
+	err = model.DB.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Locale,
 		&user.Version,
 	)
 	if err != nil {
@@ -153,11 +258,15 @@ func (model *UserModel) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (model *UserModel) UpadeteUser(user *User) error {
+func (model *UserModel) UpadeteUser(ctx context.Context, user *User) (err error) {
+	ctx, span := startSpan(ctx, "UserModel.UpadeteUser")
+	defer func() { endSpan(span, err) }()
+
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET name = $1, email = $2, password_hash = $3, activated = $4, locale = $5,
+			version = version + 1
+		WHERE id = $6 AND version = $7
 		RETURNING version
 	`
 	args := []any{
@@ -165,14 +274,15 @@ func (model *UserModel) UpadeteUser(user *User) error {
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.Locale,
 		user.ID,
 		user.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := model.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	err = model.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
@@ -187,10 +297,15 @@ func (model *UserModel) UpadeteUser(user *User) error {
 	return nil
 }
 
-func (model *UserModel) GetUserForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (model *UserModel) GetUserForToken(
+	ctx context.Context, tokenScope, tokenPlaintext string,
+) (_ *User, err error) {
+	ctx, span := startSpan(ctx, "UserModel.GetUserForToken")
+	defer func() { endSpan(span, err) }()
+
 	query := `
 		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, 
-			users.activated, users.version
+			users.activated, users.locale, users.version
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
@@ -205,17 +320,18 @@ func (model *UserModel) GetUserForToken(tokenScope, tokenPlaintext string) (*Use
 		time.Now(),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	var user User
-	err := model.DB.QueryRowContext(ctx, query, args...).Scan(
+	err = model.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Locale,
 		&user.Version,
 	)
 	if err != nil {
@@ -0,0 +1,341 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	EventMovieCreated = "movie.created"
+	EventMovieUpdated = "movie.updated"
+	EventMovieDeleted = "movie.deleted"
+)
+
+var WebhookEvents = []string{EventMovieCreated, EventMovieUpdated, EventMovieDeleted}
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateWebhook(v *validator.Validator, webhook *Webhook, validURL func(string) bool) {
+	v.CheckAdd(webhook.URL != "", "url", "must be provided")
+	v.CheckAdd(len(webhook.URL) <= 2048, "url", "cannot be more than 2048 characters")
+	v.CheckAdd(validURL(webhook.URL), "url", "must be an absolute http or https URL")
+
+	v.CheckAdd(len(webhook.Events) >= 1, "events", "must have at least one")
+	v.CheckAdd(validator.ListUnique(webhook.Events...), "events", "cannot have duplicates")
+	for _, event := range webhook.Events {
+		v.CheckAdd(
+			validator.ValueInList(event, WebhookEvents...), "events",
+			"must only contain movie.created, movie.updated or movie.deleted",
+		)
+	}
+}
+
+type WebhookDelivery struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	WebhookID  int64           `json:"webhook_id"`
+	JobID      *int64          `json:"job_id,omitempty"`
+	EventID    string          `json:"event_id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	StatusCode int             `json:"status_code"`
+	Error      string          `json:"error,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+	Succeeded  bool            `json:"succeeded"`
+}
+
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+func (model *WebhookModel) InsertWebhook(ctx context.Context, webhook *Webhook) (err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.InsertWebhook")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		INSERT INTO webhooks (url, secret, events, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version
+	`
+	args := []any{
+		webhook.URL,
+		webhook.Secret,
+		pq.Array(webhook.Events),
+		webhook.Active,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = model.DB.QueryRowContext(ctx, query, args...).Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+		&webhook.Version,
+	)
+	return err
+}
+
+func (model *WebhookModel) GetByID(ctx context.Context, id int64) (_ *Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetByID")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT id, created_at, url, secret, events, active, version
+		FROM webhooks
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var webhook Webhook
+	err = model.DB.QueryRowContext(ctx, query, id).Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.Events),
+		&webhook.Active,
+		&webhook.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+func (model *WebhookModel) GetAll(ctx context.Context, event string) (_ []*Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetAll")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		SELECT id, created_at, url, secret, events, active, version
+		FROM webhooks
+		WHERE ((active AND events @> ARRAY[$1]) OR $1 = '')
+		ORDER BY id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		var webhook Webhook
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.CreatedAt,
+			&webhook.URL,
+			&webhook.Secret,
+			pq.Array(&webhook.Events),
+			&webhook.Active,
+			&webhook.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (model *WebhookModel) DeleteByID(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.DeleteByID")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := model.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (model *WebhookModel) InsertDelivery(
+	ctx context.Context, delivery *WebhookDelivery,
+) (err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.InsertDelivery")
+	defer func() { endSpan(span, err) }()
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			webhook_id, job_id, event_id, event, payload, attempt, status_code, error,
+			duration_ms, succeeded
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	args := []any{
+		delivery.WebhookID,
+		delivery.JobID,
+		delivery.EventID,
+		delivery.Event,
+		[]byte(delivery.Payload),
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.Succeeded,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = model.DB.QueryRowContext(ctx, query, args...).Scan(&delivery.ID, &delivery.CreatedAt)
+	return err
+}
+
+const webhookDeliveryColumns = `
+	id, created_at, webhook_id, job_id, event_id, event, payload, attempt, status_code, error,
+	duration_ms, succeeded
+`
+
+func scanWebhookDelivery(
+	row interface{ Scan(...any) error }, delivery *WebhookDelivery, extra ...any,
+) error {
+	var payload []byte
+	err := row.Scan(append(extra,
+		&delivery.ID,
+		&delivery.CreatedAt,
+		&delivery.WebhookID,
+		&delivery.JobID,
+		&delivery.EventID,
+		&delivery.Event,
+		&payload,
+		&delivery.Attempt,
+		&delivery.StatusCode,
+		&delivery.Error,
+		&delivery.DurationMS,
+		&delivery.Succeeded,
+	)...)
+	if err != nil {
+		return err
+	}
+
+	delivery.Payload = payload
+	return nil
+}
+
+func (model *WebhookModel) GetDelivery(
+	ctx context.Context, webhookID, id int64,
+) (_ *WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetDelivery")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND id = $2
+	`, webhookDeliveryColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var delivery WebhookDelivery
+	err = scanWebhookDelivery(model.DB.QueryRowContext(ctx, query, webhookID, id), &delivery)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &delivery, nil
+}
+
+func (model *WebhookModel) ListDeliveries(
+	ctx context.Context, webhookID int64, filter Filter,
+) (_ []*WebhookDelivery, _ *Metadata, err error) {
+	ctx, span := startSpan(ctx, "WebhookModel.ListDeliveries")
+	defer func() { endSpan(span, err) }()
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY %s
+		LIMIT $2
+		OFFSET $3
+	`, webhookDeliveryColumns, filter.OrderBy())
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := model.DB.QueryContext(ctx, query, webhookID, filter.Limit(), filter.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	totalRecords := 0
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := scanWebhookDelivery(rows, &delivery, &totalRecords)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := NewMetadata(filter.Page, filter.PageSize, totalRecords)
+
+	return deliveries, metadata, nil
+}
@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
+)
+
+var ErrUnknownKind = errors.New("no handler registered for job kind")
+
+type HandlerFunc func(ctx context.Context, job *data.Job) error
+
+type Config struct {
+	Workers      int
+	PollInterval time.Duration
+	LockTimeout  time.Duration
+	JobTimeout   time.Duration
+	MaxBackoff   time.Duration
+
+	// OnResult, if set, is called after every attempt with how it ended: completed, failed
+	// (and due to be retried) or dead.
+	OnResult func(job *data.Job, outcome string, elapsed time.Duration)
+
+	// Tracer, if set, traces every attempt as a continuation of the trace that enqueued the job.
+	Tracer *tracing.Tracer
+}
+
+type Runner struct {
+	config   Config
+	jobs     *data.JobModel
+	logger   *jsonlog.Logger
+	handlers map[string]HandlerFunc
+	quit     chan struct{}
+	wg       sync.WaitGroup
+
+	// ctx is cancelled when Shutdown gives up waiting, which stops the queries and jobs that
+	// are still running
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRunner(jobs *data.JobModel, logger *jsonlog.Logger, config Config) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Runner{
+		config:   config,
+		jobs:     jobs,
+		logger:   logger,
+		handlers: make(map[string]HandlerFunc),
+		quit:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (runner *Runner) Handle(kind string, fn HandlerFunc) {
+	runner.handlers[kind] = fn
+}
+
+func (runner *Runner) Start() {
+	for range runner.config.Workers {
+		runner.wg.Add(1)
+		go func() {
+			defer runner.wg.Done()
+			runner.work()
+		}()
+	}
+
+	runner.logger.PrintIfo("job workers started", map[string]string{
+		"workers": strconv.Itoa(runner.config.Workers),
+	})
+}
+
+// Shutdown stops the workers from claiming new jobs and waits for the jobs already in flight to
+// finish. Jobs that are still running when ctx expires have their context cancelled, and are
+// picked up again by another worker once their lock times out.
+func (runner *Runner) Shutdown(ctx context.Context) error {
+	close(runner.quit)
+
+	done := make(chan struct{})
+	go func() {
+		runner.wg.Wait()
+		close(done)
+	}()
+
+	defer runner.cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (runner *Runner) work() {
+	for {
+		select {
+		case <-runner.quit:
+			return
+		default:
+		}
+
+		job, err := runner.jobs.Dequeue(runner.ctx, runner.config.LockTimeout)
+		if err != nil {
+			if !errors.Is(err, data.ErrNoRecord) && !errors.Is(err, context.Canceled) {
+				runner.logger.PrintError(err, nil)
+			}
+
+			// with nothing ready to run, this is a good time to deal with the jobs whose lock
+			// timed out on their last attempt, which would otherwise stay running forever
+			_, err = runner.jobs.BuryExpired(runner.ctx, runner.config.LockTimeout)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				runner.logger.PrintError(err, nil)
+			}
+
+			select {
+			case <-runner.quit:
+				return
+			case <-time.After(runner.config.PollInterval):
+			}
+			continue
+		}
+
+		runner.run(job)
+	}
+}
+
+func (runner *Runner) run(job *data.Job) {
+	properties := map[string]string{
+		"job_id":   strconv.FormatInt(job.ID, 10),
+		"kind":     job.Kind,
+		"attempts": strconv.Itoa(job.Attempts),
+	}
+
+	ctx, span := runner.startSpan(job)
+	defer span.End()
+	logger := runner.logger.WithContext(ctx)
+
+	start := time.Now()
+	err := runner.execute(ctx, job)
+	elapsed := time.Since(start)
+	if err == nil {
+		runner.report(job, "completed", elapsed)
+		err = runner.jobs.Complete(ctx, job)
+		if err != nil {
+			logger.PrintError(err, properties)
+		}
+		return
+	}
+
+	span.RecordError(err)
+	logger.PrintError(err, properties)
+
+	err = runner.jobs.Fail(ctx, job, err, time.Now().Add(runner.backoff(job.Attempts)))
+	if err != nil {
+		runner.report(job, "failed", elapsed)
+		logger.PrintError(err, properties)
+		return
+	}
+
+	if job.Status == data.JobDead {
+		runner.report(job, "dead", elapsed)
+		logger.PrintIfo("job moved to dead letter", properties)
+		return
+	}
+
+	runner.report(job, "failed", elapsed)
+}
+
+func (runner *Runner) report(job *data.Job, outcome string, elapsed time.Duration) {
+	if runner.config.OnResult != nil {
+		runner.config.OnResult(job, outcome, elapsed)
+	}
+}
+
+func (runner *Runner) startSpan(job *data.Job) (context.Context, *tracing.Span) {
+	ctx := runner.ctx
+	if runner.config.Tracer == nil {
+		return ctx, nil
+	}
+
+	if parent, ok := tracing.ParseTraceparent(job.Traceparent); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, parent)
+	}
+
+	return runner.config.Tracer.Start(
+		ctx, "job "+job.Kind, tracing.SpanKindConsumer,
+		tracing.Int64("greenlight.job.id", job.ID),
+		tracing.Int("greenlight.job.attempt", job.Attempts),
+	)
+}
+
+func (runner *Runner) execute(ctx context.Context, job *data.Job) (err error) {
+	fn, ok := runner.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKind, job.Kind)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, runner.config.JobTimeout)
+	defer cancel()
+
+	return fn(ctx, job)
+}
+
+func (runner *Runner) backoff(attempts int) time.Duration {
+	delay := runner.config.MaxBackoff
+	if attempts < 32 {
+		delay = min(time.Second<<attempts, runner.config.MaxBackoff)
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	return delay + jitter
+}
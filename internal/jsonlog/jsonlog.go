@@ -1,12 +1,16 @@
 package jsonlog
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"maps"
 	"os"
 	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/tracing"
 )
 
 type Level int8
@@ -32,16 +36,64 @@ func (l Level) String() string {
 }
 
 type Logger struct {
-	out      io.Writer
-	minLevel Level
-	mu       sync.Mutex
+	out        io.Writer
+	minLevel   Level
+	mu         *sync.Mutex
+	traceID    string
+	spanID     string
+	properties map[string]string
 }
 
 func NewLogger(out io.Writer, minLevel Level) *Logger {
 	return &Logger{
 		out:      out,
 		minLevel: minLevel,
+		mu:       &sync.Mutex{},
+	}
+}
+
+type contextKey struct{}
+
+// WithProperties returns a copy of ctx carrying properties, on top of any it already carries, for
+// loggers obtained through WithContext to add to every entry.
+func WithProperties(ctx context.Context, properties map[string]string) context.Context {
+	merged := maps.Clone(propertiesFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(properties))
+	}
+	maps.Copy(merged, properties)
+
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+func propertiesFromContext(ctx context.Context) map[string]string {
+	properties, _ := ctx.Value(contextKey{}).(map[string]string)
+	return properties
+}
+
+// WithContext returns a logger that adds the properties in ctx and the IDs of its trace and span
+// to its entries, so they can be matched up with the request and the exported spans. When ctx
+// carries neither it returns logger itself.
+func (logger *Logger) WithContext(ctx context.Context) *Logger {
+	sc := tracing.SpanContextFromContext(ctx)
+	properties := propertiesFromContext(ctx)
+	if !sc.IsValid() && properties == nil {
+		return logger
+	}
+
+	child := *logger
+	if sc.IsValid() {
+		child.traceID = sc.TraceID.String()
+		child.spanID = sc.SpanID.String()
+	}
+	if properties != nil {
+		child.properties = maps.Clone(logger.properties)
+		if child.properties == nil {
+			child.properties = make(map[string]string, len(properties))
+		}
+		maps.Copy(child.properties, properties)
 	}
+	return &child
 }
 
 func (logger *Logger) PrintIfo(message string, properties map[string]string) {
@@ -62,17 +114,27 @@ func (logger *Logger) print(level Level, message string, properties map[string]s
 		return -1, nil
 	}
 
+	if logger.properties != nil {
+		merged := maps.Clone(logger.properties)
+		maps.Copy(merged, properties)
+		properties = merged
+	}
+
 	aux := struct {
 		Level      string            `json:"level"`
 		Message    string            `json:"message"`
 		Properties map[string]string `json:"properties,omitempty"`
 		Time       string            `json:"time"`
+		TraceID    string            `json:"trace_id,omitempty"`
+		SpanID     string            `json:"span_id,omitempty"`
 		Trace      string            `json:"trace,omitempty"`
 	}{
 		Level:      level.String(),
 		Message:    message,
 		Properties: properties,
 		Time:       time.Now().UTC().Format(time.RFC3339),
+		TraceID:    logger.traceID,
+		SpanID:     logger.spanID,
 	}
 
 	if level >= LevelError {
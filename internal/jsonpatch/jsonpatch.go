@@ -0,0 +1,325 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPointer   = errors.New("invalid JSON pointer")
+	ErrPathNotFound     = errors.New("path does not exist")
+	ErrInvalidOperation = errors.New("invalid operation")
+	ErrTestFailed       = errors.New("test failed")
+)
+
+// MergePatch applies an RFC 7396 merge patch to target and returns the result. Both values are
+// expected to come from encoding/json decoding into an any, and target may be modified in place.
+func MergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = MergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies an RFC 6902 JSON patch to doc and returns the result. The operations are applied
+// in order and the first one that fails stops the patch; doc may already have been modified by
+// then, so callers should discard it on error.
+func Apply(doc any, ops []Operation) (any, error) {
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyOperation(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", ErrInvalidOperation)
+		}
+
+		var value any
+		err := json.Unmarshal(op.Value, &value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, deepCopy(value))
+		}
+
+		if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidOperation)
+		}
+
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidOperation, op.Op)
+	}
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q must start with /", ErrInvalidPointer, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, appending bool) (int, error) {
+	if appending && token == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || token != strconv.Itoa(index) {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPointer, token)
+	}
+
+	limit := length - 1
+	if appending {
+		limit = length
+	}
+	if index > limit {
+		return 0, ErrPathNotFound
+	}
+
+	return index, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+
+	return doc, nil
+}
+
+// update walks to the container holding the last token of path and replaces it with what fn
+// returns, rebuilding the parents on the way out since slices cannot be changed in place.
+func update(doc any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = updated
+		return node, nil
+	case []any:
+		index, err := arrayIndex(path[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := update(node[index], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:index], append([]any{value}, node[index:]...)...), nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, ErrPathNotFound
+			}
+			node[token] = value
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return node, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidOperation)
+	}
+
+	var removed any
+	doc, err := update(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, removed, nil
+}
+
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, child := range value {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, child := range value {
+			copied[i] = deepCopy(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,206 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+
+	var value any
+	err := json.Unmarshal([]byte(s), &value)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+
+	return value
+}
+
+func decodeOperations(t *testing.T, s string) []Operation {
+	t.Helper()
+
+	var ops []Operation
+	err := json.Unmarshal([]byte(s), &ops)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+
+	return ops
+}
+
+func TestMergePatch(t *testing.T) {
+	// the examples from RFC 7396, appendix A
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			got := MergePatch(decode(t, tt.target), decode(t, tt.patch))
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v; want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		ops  string
+		want string
+	}{
+		{
+			"replace a field",
+			`{"title":"Moana","year":2016}`,
+			`[{"op":"replace","path":"/title","value":"Moana 2"}]`,
+			`{"title":"Moana 2","year":2016}`,
+		},
+		{
+			"add a field",
+			`{"title":"Moana"}`,
+			`[{"op":"add","path":"/runtime","value":107}]`,
+			`{"title":"Moana","runtime":107}`,
+		},
+		{
+			"add inserts into an array",
+			`{"genres":["animation","family"]}`,
+			`[{"op":"add","path":"/genres/1","value":"adventure"}]`,
+			`{"genres":["animation","adventure","family"]}`,
+		},
+		{
+			"add appends with -",
+			`{"genres":["animation"]}`,
+			`[{"op":"add","path":"/genres/-","value":"family"}]`,
+			`{"genres":["animation","family"]}`,
+		},
+		{
+			"remove an array element",
+			`{"genres":["animation","adventure","family"]}`,
+			`[{"op":"remove","path":"/genres/1"}]`,
+			`{"genres":["animation","family"]}`,
+		},
+		{
+			"move a field",
+			`{"name":"Moana"}`,
+			`[{"op":"move","from":"/name","path":"/title"}]`,
+			`{"title":"Moana"}`,
+		},
+		{
+			"copy does not alias the source",
+			`{"genres":["animation"]}`,
+			`[
+				{"op":"copy","from":"/genres","path":"/tags"},
+				{"op":"add","path":"/tags/-","value":"disney"}
+			]`,
+			`{"genres":["animation"],"tags":["animation","disney"]}`,
+		},
+		{
+			"test then replace",
+			`{"title":"Moana","version":3}`,
+			`[
+				{"op":"test","path":"/version","value":3},
+				{"op":"replace","path":"/title","value":"Moana 2"}
+			]`,
+			`{"title":"Moana 2","version":3}`,
+		},
+		{
+			"escaped pointer tokens",
+			`{"a/b":1,"c~d":2}`,
+			`[
+				{"op":"replace","path":"/a~1b","value":10},
+				{"op":"remove","path":"/c~0d"}
+			]`,
+			`{"a/b":10}`,
+		},
+		{
+			"replace the whole document",
+			`{"title":"Moana"}`,
+			`[{"op":"replace","path":"","value":{"title":"Coco"}}]`,
+			`{"title":"Coco"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(decode(t, tt.doc), decodeOperations(t, tt.ops))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v; want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  string
+		want error
+	}{
+		{"unknown op", `[{"op":"merge","path":"/title"}]`, ErrInvalidOperation},
+		{"missing value", `[{"op":"add","path":"/title"}]`, ErrInvalidOperation},
+		{"pointer without slash", `[{"op":"remove","path":"title"}]`, ErrInvalidPointer},
+		{"replace missing field", `[{"op":"replace","path":"/x","value":1}]`, ErrPathNotFound},
+		{"remove missing field", `[{"op":"remove","path":"/runtime"}]`, ErrPathNotFound},
+		{"add below missing field", `[{"op":"add","path":"/a/b","value":1}]`, ErrPathNotFound},
+		{"array index out of range", `[{"op":"remove","path":"/genres/2"}]`, ErrPathNotFound},
+		{"array index with sign", `[{"op":"remove","path":"/genres/+0"}]`, ErrInvalidPointer},
+		{"zero-padded array index", `[{"op":"remove","path":"/genres/01"}]`, ErrInvalidPointer},
+		{"- outside add", `[{"op":"replace","path":"/genres/-","value":"x"}]`, ErrInvalidPointer},
+		{"remove whole document", `[{"op":"remove","path":""}]`, ErrInvalidOperation},
+		{
+			"move into itself",
+			`[{"op":"move","from":"/genres","path":"/genres/0"}]`,
+			ErrInvalidOperation,
+		},
+		{"move from missing field", `[{"op":"move","from":"/x","path":"/y"}]`, ErrPathNotFound},
+		{"test mismatch", `[{"op":"test","path":"/title","value":"Coco"}]`, ErrTestFailed},
+		{
+			"later operation fails",
+			`[
+				{"op":"replace","path":"/title","value":"Moana 2"},
+				{"op":"test","path":"/version","value":4}
+			]`,
+			ErrTestFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decode(t, `{"title":"Moana","genres":["animation","family"],"version":3}`)
+
+			got, err := Apply(doc, decodeOperations(t, tt.ops))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v; want %v", err, tt.want)
+			}
+			if got != nil {
+				t.Errorf("got document %v on error; want nil", got)
+			}
+		})
+	}
+}
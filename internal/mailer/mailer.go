@@ -2,66 +2,206 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
 	"text/template"
-	"time"
 
-	"github.com/go-mail/mail/v2"
+	"github.com/Yusufdot101/greenlight/internal/tracing"
 )
 
 //go:embed "templates"
 var templateFS embed.FS
 
+const DefaultLocale = "en"
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+var requiredBlocks = []string{"subject", "plainBody", "htmlBody"}
+
 type Mailer struct {
-	dialer *mail.Dialer
-	sender string
+	transport Transport
+	sender    string
+	templates map[string]map[string]*emailTemplate
 }
 
-func NewMailer(host string, port int, username, password, sender string) *Mailer {
-	dialer := mail.NewDialer(host, port, username, password)
-	dialer.Timeout = 5 * time.Second
+// emailTemplate holds a template file parsed twice: the subject and plainBody blocks are
+// rendered as text, while the htmlBody block is rendered with html/template so that values such
+// as a user's name are escaped.
+type emailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+func NewMailer(transport Transport, sender string) (*Mailer, error) {
+	templates, err := parseTemplates(templateFS)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Mailer{
-		dialer: dialer,
-		sender: sender,
+		transport: transport,
+		sender:    sender,
+		templates: templates,
+	}, nil
+}
+
+// parseTemplates loads every template under templates/ keyed by locale and name. Templates in
+// the root of the directory belong to DefaultLocale and localised variants live in a directory
+// named after their locale, e.g. templates/fr/user_welcome.tmpl.html.
+func parseTemplates(fsys fs.FS) (map[string]map[string]*emailTemplate, error) {
+	templates := map[string]map[string]*emailTemplate{DefaultLocale: {}}
+
+	err := fs.WalkDir(fsys, "templates", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(file, ".tmpl.html") {
+			return err
+		}
+
+		locale := strings.TrimPrefix(path.Dir(file), "templates")
+		locale = strings.TrimPrefix(locale, "/")
+		if locale == "" {
+			locale = DefaultLocale
+		}
+		name := strings.TrimSuffix(path.Base(file), ".tmpl.html")
+
+		text, err := template.New(name).ParseFS(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		html, err := htmltemplate.New(name).ParseFS(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		for _, block := range requiredBlocks {
+			if text.Lookup(block) == nil {
+				return fmt.Errorf("email template %s does not define %q", file, block)
+			}
+		}
+
+		if templates[locale] == nil {
+			templates[locale] = make(map[string]*emailTemplate)
+		}
+		templates[locale][name] = &emailTemplate{text: text, html: html}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for locale, localised := range templates {
+		for name := range localised {
+			if _, exists := templates[DefaultLocale][name]; !exists {
+				return nil, fmt.Errorf(
+					"email template %s/%s has no %s default", locale, name, DefaultLocale,
+				)
+			}
+		}
 	}
+
+	return templates, nil
 }
 
-func (mailer *Mailer) Send(recipient string, templateFile string, data any) error {
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+func (mailer *Mailer) Templates() []string {
+	names := make([]string, 0, len(mailer.templates[DefaultLocale]))
+	for name := range mailer.templates[DefaultLocale] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (mailer *Mailer) Locales() []string {
+	locales := make([]string, 0, len(mailer.templates))
+	for locale := range mailer.templates {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+	return locales
+}
+
+// resolve picks the variant of the named template for the first of the preferred locales that
+// has one, trying the base language of a regional tag (pt for pt-br) before moving on.
+func (mailer *Mailer) resolve(name string, locales []string) (*emailTemplate, string, error) {
+	for _, locale := range locales {
+		locale = strings.ToLower(locale)
+		base, _, _ := strings.Cut(locale, "-")
+
+		for _, candidate := range []string{locale, base} {
+			if tmpl, exists := mailer.templates[candidate][name]; exists {
+				return tmpl, candidate, nil
+			}
+		}
+	}
+
+	tmpl, exists := mailer.templates[DefaultLocale][name]
+	if !exists {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	return tmpl, DefaultLocale, nil
+}
+
+func (mailer *Mailer) Render(name string, locales []string, data any) (*Message, string, error) {
+	tmpl, locale, err := mailer.resolve(name, locales)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	err = tmpl.text.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	err = tmpl.text.ExecuteTemplate(plainBody, "plainBody", data)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	err = tmpl.html.ExecuteTemplate(htmlBody, "htmlBody", data)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+
+	msg := &Message{
+		From:      mailer.sender,
+		Subject:   subject.String(),
+		PlainBody: plainBody.String(),
+		HTMLBody:  htmlBody.String(),
 	}
 
-	msg := mail.NewMessage()
-	msg.SetHeader("To", recipient)
-	msg.SetHeader("From", mailer.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
+	return msg, locale, nil
+}
+
+func (mailer *Mailer) Send(
+	ctx context.Context, recipient, name string, locales []string, data any,
+) error {
+	_, span := tracing.Start(
+		ctx, "Mailer.Send", tracing.SpanKindClient,
+		tracing.String("greenlight.email.template", name),
+	)
+	defer span.End()
 
-	err = mailer.dialer.DialAndSend(msg)
+	msg, locale, err := mailer.Render(name, locales, data)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
+	span.SetAttributes(tracing.String("greenlight.email.locale", locale))
 
-	return nil
+	msg.To = recipient
+	err = mailer.transport.Send(msg)
+	span.RecordError(err)
+	return err
 }
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMailerSend(t *testing.T) {
+	templateData := map[string]any{
+		"Name":            `<script>alert("hi")</script>`,
+		"userID":          int64(42),
+		"activationToken": "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	}
+
+	tests := []struct {
+		name    string
+		locales []string
+		subject string
+	}{
+		{"no preference", nil, "Hi "},
+		{"exact locale", []string{"fr"}, "Bienvenue sur Greenlight"},
+		{"regional locale", []string{"fr-CA"}, "Bienvenue sur Greenlight"},
+		{"first supported locale", []string{"de", "fr"}, "Bienvenue sur Greenlight"},
+		{"unsupported locale", []string{"de"}, "Hi "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := NewMemoryTransport()
+			mailer, err := NewMailer(transport, "Greenlight <no-reply@greenlight.test>")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = mailer.Send(
+				context.Background(), "alice@example.com", "user_welcome", tt.locales, templateData,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages := transport.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("got %d messages; want 1", len(messages))
+			}
+			msg := messages[0]
+
+			if msg.To != "alice@example.com" {
+				t.Errorf("got recipient %q; want %q", msg.To, "alice@example.com")
+			}
+			if msg.From != "Greenlight <no-reply@greenlight.test>" {
+				t.Errorf("got sender %q", msg.From)
+			}
+			if !strings.HasPrefix(msg.Subject, tt.subject) {
+				t.Errorf("got subject %q; want it to start with %q", msg.Subject, tt.subject)
+			}
+			if !strings.Contains(msg.PlainBody, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+				t.Error("plain body does not contain the activation token")
+			}
+			if strings.Contains(msg.HTMLBody, "<script>") {
+				t.Error("html body contains the user's name unescaped")
+			}
+			if !strings.Contains(msg.HTMLBody, "&lt;script&gt;") {
+				t.Error("html body does not contain the user's name escaped")
+			}
+		})
+	}
+}
+
+func TestMailerSendUnknownTemplate(t *testing.T) {
+	transport := NewMemoryTransport()
+	mailer, err := NewMailer(transport, "no-reply@greenlight.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(context.Background(), "alice@example.com", "missing", nil, nil)
+	if !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("got error %v; want ErrUnknownTemplate", err)
+	}
+	if len(transport.Messages()) != 0 {
+		t.Error("a message was sent for an unknown template")
+	}
+}
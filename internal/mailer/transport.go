@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+	"github.com/go-mail/mail/v2"
+)
+
+type Message struct {
+	To        string
+	From      string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+func (msg *Message) mime() *mail.Message {
+	m := mail.NewMessage()
+	m.SetHeader("To", msg.To)
+	m.SetHeader("From", msg.From)
+	m.SetHeader("Subject", msg.Subject)
+	m.SetDateHeader("Date", time.Now())
+	m.SetBody("text/plain", msg.PlainBody)
+	m.AddAlternative("text/html", msg.HTMLBody)
+	return m
+}
+
+type Transport interface {
+	Send(msg *Message) error
+}
+
+type SMTPTransport struct {
+	dialer *mail.Dialer
+}
+
+func NewSMTPTransport(host string, port int, username, password string) *SMTPTransport {
+	dialer := mail.NewDialer(host, port, username, password)
+	dialer.Timeout = 5 * time.Second
+
+	return &SMTPTransport{dialer: dialer}
+}
+
+func (transport *SMTPTransport) Send(msg *Message) error {
+	return transport.dialer.DialAndSend(msg.mime())
+}
+
+// FileTransport delivers every message as an .eml file into a maildir, writing it under tmp/
+// first and then moving it into new/ so that mail clients never see a partial message.
+type FileTransport struct {
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileTransport{dir: dir}, nil
+}
+
+func (transport *FileTransport) Send(msg *Message) error {
+	name := fmt.Sprintf(
+		"%d.%d_%d.greenlight.eml", time.Now().UnixNano(), os.Getpid(), transport.counter.Add(1),
+	)
+	tmpPath := filepath.Join(transport.dir, "tmp", name)
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = msg.mime().WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(transport.dir, "new", name))
+}
+
+// LogTransport writes messages to the log instead of delivering them. The plain text body is
+// logged in full so that activation links can be followed in development, which is also why it
+// must not be used anywhere else.
+type LogTransport struct {
+	logger *jsonlog.Logger
+}
+
+func NewLogTransport(logger *jsonlog.Logger) *LogTransport {
+	return &LogTransport{logger: logger}
+}
+
+func (transport *LogTransport) Send(msg *Message) error {
+	transport.logger.PrintIfo("email sent", map[string]string{
+		"to":         msg.To,
+		"from":       msg.From,
+		"subject":    msg.Subject,
+		"plain_body": msg.PlainBody,
+	})
+	return nil
+}
+
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (transport *MemoryTransport) Send(msg *Message) error {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	transport.messages = append(transport.messages, *msg)
+	return nil
+}
+
+func (transport *MemoryTransport) Messages() []Message {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	messages := make([]Message, len(transport.messages))
+	copy(messages, transport.messages)
+	return messages
+}
+
+func (transport *MemoryTransport) Reset() {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	transport.messages = nil
+}
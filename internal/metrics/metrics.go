@@ -0,0 +1,381 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the Prometheus text exposition format written by Registry.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets in seconds, the same as the Prometheus client's,
+// which suit request latencies from a few milliseconds to ten seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Sample is a single value of a metric, as reported by a collector function at scrape time.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (registry *Registry) register(name string, m metric) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if registry.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	registry.names[name] = true
+	registry.metrics = append(registry.metrics, m)
+}
+
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mu.Lock()
+	metrics := slices.Clone(registry.metrics)
+	registry.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+
+	return buffered.Flush()
+}
+
+func (registry *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		registry.Write(w)
+	})
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, helpEscaper.Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+}
+
+// writeSample writes one line of the metric, with extra holding label name and value pairs that
+// are not part of the series itself, like the le label of histogram buckets.
+func (d *desc) writeSample(
+	w *bufio.Writer, suffix string, labels []string, value float64, extra ...string,
+) {
+	w.WriteString(d.name)
+	w.WriteString(suffix)
+
+	pairs := make([]string, 0, len(labels)+len(extra)/2)
+	for i, label := range labels {
+		pairs = append(pairs, d.labelNames[i]+`="`+labelEscaper.Replace(label)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+labelEscaper.Replace(extra[i+1])+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// vec keeps one value per combination of label values, written out in sorted order so the
+// output is stable between scrapes.
+type vec[T any] struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*series[T]
+	create func() *T
+}
+
+type series[T any] struct {
+	labels []string
+	value  *T
+}
+
+func (v *vec[T]) with(labels []string) *T {
+	if len(labels) != len(v.labelNames) {
+		panic(fmt.Sprintf(
+			"metrics: %s expects %d label values, got %d", v.name, len(v.labelNames), len(labels),
+		))
+	}
+
+	key := strings.Join(labels, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{labels: slices.Clone(labels), value: v.create()}
+		v.series[key] = s
+	}
+
+	return s.value
+}
+
+func (v *vec[T]) sorted() []*series[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	sorted := make([]*series[T], len(keys))
+	for i, key := range keys {
+		sorted[i] = v.series[key]
+	}
+
+	return sorted
+}
+
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) set(n float64) {
+	v.mu.Lock()
+	v.v = n
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+type Counter struct{ v *value }
+
+func (c Counter) Inc() { c.v.add(1) }
+
+// Add increases the counter by delta, which must not be negative.
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+type CounterVec struct {
+	vec[value]
+}
+
+func (registry *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{vec[value]{
+		desc:   desc{name: name, help: help, typ: "counter", labelNames: labelNames},
+		series: make(map[string]*series[value]),
+		create: func() *value { return &value{} },
+	}}
+	registry.register(name, c)
+	return c
+}
+
+func (c *CounterVec) With(labels ...string) Counter {
+	return Counter{c.with(labels)}
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, s := range c.sorted() {
+		c.writeSample(w, "", s.labels, s.value.get())
+	}
+}
+
+type Gauge struct{ v *value }
+
+func (g Gauge) Set(n float64)     { g.v.set(n) }
+func (g Gauge) Add(delta float64) { g.v.add(delta) }
+func (g Gauge) Inc()              { g.v.add(1) }
+func (g Gauge) Dec()              { g.v.add(-1) }
+
+type GaugeVec struct {
+	vec[value]
+}
+
+func (registry *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{vec[value]{
+		desc:   desc{name: name, help: help, typ: "gauge", labelNames: labelNames},
+		series: make(map[string]*series[value]),
+		create: func() *value { return &value{} },
+	}}
+	registry.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) With(labels ...string) Gauge {
+	return Gauge{g.with(labels)}
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	for _, s := range g.sorted() {
+		g.writeSample(w, "", s.labels, s.value.get())
+	}
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type Histogram struct {
+	buckets []float64
+	h       *histogram
+}
+
+func (h Histogram) Observe(v float64) {
+	h.h.mu.Lock()
+	defer h.h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.h.counts[i]++
+			break
+		}
+	}
+	h.h.sum += v
+	h.h.count++
+}
+
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+// NewHistogramVec creates a histogram with the given upper bucket bounds, which must be sorted
+// in increasing order. The +Inf bucket is added implicitly.
+func (registry *Registry) NewHistogramVec(
+	name, help string, buckets []float64, labelNames ...string,
+) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+
+	h := &HistogramVec{
+		vec: vec[histogram]{
+			desc:   desc{name: name, help: help, typ: "histogram", labelNames: labelNames},
+			series: make(map[string]*series[histogram]),
+			create: func() *histogram {
+				return &histogram{counts: make([]uint64, len(buckets))}
+			},
+		},
+		buckets: buckets,
+	}
+	registry.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) With(labels ...string) Histogram {
+	return Histogram{buckets: h.buckets, h: h.with(labels)}
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		s.value.mu.Lock()
+		counts := slices.Clone(s.value.counts)
+		sum, count := s.value.sum, s.value.count
+		s.value.mu.Unlock()
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += counts[i]
+			h.writeSample(w, "_bucket", s.labels, float64(cumulative), "le", formatFloat(upper))
+		}
+		h.writeSample(w, "_bucket", s.labels, float64(count), "le", "+Inf")
+		h.writeSample(w, "_sum", s.labels, sum)
+		h.writeSample(w, "_count", s.labels, float64(count))
+	}
+}
+
+// collector reports values that already live elsewhere, such as connection pool statistics,
+// by calling collect every time the registry is scraped.
+type collector struct {
+	desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples are returned by collect at scrape time.
+func (registry *Registry) NewGaugeFunc(
+	name, help string, collect func() []Sample, labelNames ...string,
+) {
+	registry.register(name, &collector{
+		desc:    desc{name: name, help: help, typ: "gauge", labelNames: labelNames},
+		collect: collect,
+	})
+}
+
+// NewCounterFunc registers a counter whose samples are returned by collect at scrape time. The
+// values must never decrease.
+func (registry *Registry) NewCounterFunc(
+	name, help string, collect func() []Sample, labelNames ...string,
+) {
+	registry.register(name, &collector{
+		desc:    desc{name: name, help: help, typ: "counter", labelNames: labelNames},
+		collect: collect,
+	})
+}
+
+func (c *collector) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, sample := range c.collect() {
+		c.writeSample(w, "", sample.Labels, sample.Value)
+	}
+}
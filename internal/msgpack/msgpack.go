@@ -0,0 +1,304 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+var (
+	ErrUnsupportedType = errors.New("msgpack: unsupported type")
+	ErrTruncated       = errors.New("msgpack: unexpected end of data")
+)
+
+// Marshal encodes a value built from the types encoding/json decodes into an any: nil, bool,
+// float64 or json.Number, string, []any and map[string]any. Map keys are written in sorted
+// order so the output is deterministic.
+func Marshal(v any) ([]byte, error) {
+	return appendValue(nil, v)
+}
+
+func appendValue(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendInt(b, n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%w: number %s", ErrUnsupportedType, v)
+		}
+		return appendFloat(b, f), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return appendInt(b, int64(v)), nil
+		}
+		return appendFloat(b, v), nil
+	case string:
+		return appendString(b, v), nil
+	case []any:
+		b = appendHeader(b, len(v), 0x90, 15, 0xdc)
+		for _, item := range v {
+			var err error
+			b, err = appendValue(b, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		b = appendHeader(b, len(v), 0x80, 15, 0xde)
+		for _, key := range keys {
+			b = appendString(b, key)
+
+			var err error
+			b, err = appendValue(b, v[key])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+	}
+}
+
+// appendHeader writes the length prefix of a string, array or map, using the fix format when
+// the length fits in it and the 16 or 32 bit format that follows code16 otherwise.
+func appendHeader(b []byte, n int, fix byte, fixMax int, code16 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code16+1), uint32(n))
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	if len(s) > 31 && len(s) <= math.MaxUint8 {
+		b = append(b, 0xd9, byte(len(s)))
+	} else {
+		b = appendHeader(b, len(s), 0xa0, 31, 0xda)
+	}
+	return append(b, s...)
+}
+
+func appendInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 127:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+}
+
+// Unmarshal decodes a single msgpack value into the same types encoding/json would produce,
+// with integers as json.Number so they survive being re-encoded as JSON. Binary, extension and
+// timestamp values, and maps with non-string keys, are rejected.
+func Unmarshal(data []byte) (any, error) {
+	d := decoder{data: data}
+
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.offset != len(d.data) {
+		return nil, errors.New("msgpack: data contains more than one value")
+	}
+
+	return v, nil
+}
+
+const maxDepth = 64
+
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.offset < n {
+		return nil, ErrTruncated
+	}
+
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b, nil
+}
+
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("msgpack: data is nested too deeply")
+	}
+
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return json.Number(fmt.Sprint(code)), nil
+	case code >= 0xe0:
+		return json.Number(fmt.Sprint(int8(code))), nil
+	case code&0xf0 == 0x80:
+		return d.mapValue(int(code&0x0f), depth)
+	case code&0xf0 == 0x90:
+		return d.array(int(code&0x0f), depth)
+	case code&0xe0 == 0xa0:
+		return d.string(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(fmt.Sprint(n)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend from the encoded width
+		shift := 64 - 8*size
+		return json.Number(fmt.Sprint(int64(n<<shift) >> shift)), nil
+	case 0xca:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 0xcb:
+		n, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.string(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n), depth)
+	default:
+		return nil, fmt.Errorf("%w: code 0x%02x", ErrUnsupportedType, code)
+	}
+}
+
+func (d *decoder) string(n int) (string, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *decoder) array(n int, depth int) ([]any, error) {
+	// every element takes at least a byte, which stops a forged length allocating a huge slice
+	if n > len(d.data)-d.offset {
+		return nil, ErrTruncated
+	}
+
+	items := make([]any, n)
+	for i := range items {
+		var err error
+		items[i], err = d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func (d *decoder) mapValue(n int, depth int) (map[string]any, error) {
+	if n > len(d.data)-d.offset {
+		return nil, ErrTruncated
+	}
+
+	m := make(map[string]any, n)
+	for range n {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map keys must be strings", ErrUnsupportedType)
+		}
+
+		m[s], err = d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
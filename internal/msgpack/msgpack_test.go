@@ -0,0 +1,157 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		`null`,
+		`true`,
+		`false`,
+		`0`,
+		`127`,
+		`128`,
+		`-32`,
+		`-33`,
+		`2147483648`,
+		`-9223372036854775808`,
+		`1.5`,
+		`""`,
+		`"Moana"`,
+		`"` + strings.Repeat("a", 32) + `"`,
+		`"` + strings.Repeat("a", 256) + `"`,
+		`[]`,
+		`[1,"two",null,[3]]`,
+		`{}`,
+		`{"movie":{"genres":["animation","family"],"id":1,"runtime":"107 mins","title":"Moana"}}`,
+	}
+
+	for _, JSON := range tests {
+		name := JSON
+		if len(name) > 40 {
+			name = name[:40]
+		}
+
+		t.Run(name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(JSON))
+			decoder.UseNumber()
+
+			var value any
+			err := decoder.Decode(&value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := Unmarshal(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != JSON {
+				t.Errorf("got %s; want %s", got, JSON)
+			}
+		})
+	}
+}
+
+func TestMarshalFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"positive fixint", json.Number("127"), "7f"},
+		{"int32", json.Number("128"), "d200000080"},
+		{"negative fixint", json.Number("-32"), "e0"},
+		{"negative int32", json.Number("-33"), "d2ffffffdf"},
+		{"int64", json.Number("4294967296"), "d30000000100000000"},
+		{"whole float64", float64(3), "03"},
+		{"float64", 1.5, "cb3ff8000000000000"},
+		{"fixstr", "hi", "a26869"},
+		{"str8", strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{"fixarray", []any{true, false, nil}, "93c3c2c0"},
+		{"sorted fixmap", map[string]any{"b": true, "a": nil}, "82a161c0a162c3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(b); got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalUnsupportedType(t *testing.T) {
+	_, err := Marshal(map[string]any{"id": int64(1)})
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got error %v; want ErrUnsupportedType", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "", ErrTruncated},
+		{"truncated int32", "d20000", ErrTruncated},
+		{"truncated string", "a36869", ErrTruncated},
+		{"forged array length", "dcffff01", ErrTruncated},
+		{"forged map length", "deffff", ErrTruncated},
+		{"binary", "c4016e", ErrUnsupportedType},
+		{"extension", "d40100", ErrUnsupportedType},
+		{"non-string key", "8101c0", ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Unmarshal(data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRejectsTrailingData(t *testing.T) {
+	_, err := Unmarshal([]byte{0xc0, 0xc0})
+	if err == nil {
+		t.Error("got no error for two values")
+	}
+}
+
+func TestUnmarshalRejectsDeepNesting(t *testing.T) {
+	data := bytes.Repeat([]byte{0x91}, maxDepth+2)
+	data = append(data, 0xc0)
+
+	_, err := Unmarshal(data)
+	if err == nil {
+		t.Error("got no error for data nested past the limit")
+	}
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+)
+
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event *data.OutboxEvent) error
+}
+
+type Config struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	PublishTimeout time.Duration
+	Retention      time.Duration
+}
+
+// Relay moves events from the outbox table to every sink. An event is only marked as published
+// once all sinks have accepted it, so a sink may see the same event more than once after a
+// failure or a crash; consumers are expected to deduplicate on the event ID.
+type Relay struct {
+	config Config
+	outbox *data.OutboxModel
+	logger *jsonlog.Logger
+	sinks  []Sink
+	wake   chan struct{}
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+func NewRelay(
+	outbox *data.OutboxModel, logger *jsonlog.Logger, config Config, sinks ...Sink,
+) *Relay {
+	return &Relay{
+		config: config,
+		outbox: outbox,
+		logger: logger,
+		sinks:  sinks,
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (relay *Relay) Start() {
+	go func() {
+		defer close(relay.done)
+		relay.run()
+	}()
+
+	relay.logger.PrintIfo("outbox relay started", map[string]string{
+		"sinks": strconv.Itoa(len(relay.sinks)),
+	})
+}
+
+// Notify wakes the relay up without waiting for the next poll. Events written by other
+// instances are still picked up on the poll interval.
+func (relay *Relay) Notify() {
+	select {
+	case relay.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (relay *Relay) Shutdown(ctx context.Context) error {
+	close(relay.quit)
+
+	select {
+	case <-relay.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (relay *Relay) run() {
+	lastPrune := time.Time{}
+
+	for {
+		select {
+		case <-relay.quit:
+			return
+		default:
+		}
+
+		n, err := relay.outbox.Relay(context.Background(), relay.config.BatchSize, relay.publish)
+		if err != nil {
+			relay.logger.PrintError(err, map[string]string{"published": strconv.Itoa(n)})
+		}
+
+		if relay.config.Retention > 0 && time.Since(lastPrune) > time.Hour {
+			_, err := relay.outbox.DeletePublished(
+				context.Background(), time.Now().Add(-relay.config.Retention),
+			)
+			if err != nil {
+				relay.logger.PrintError(err, nil)
+			}
+			lastPrune = time.Now()
+		}
+
+		// a full batch means there is probably more waiting, so go again straight away
+		if err == nil && n == relay.config.BatchSize {
+			continue
+		}
+
+		select {
+		case <-relay.quit:
+			return
+		case <-relay.wake:
+		case <-time.After(relay.config.PollInterval):
+		}
+	}
+}
+
+func (relay *Relay) publish(event *data.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), relay.config.PublishTimeout)
+	defer cancel()
+
+	for _, sink := range relay.sinks {
+		err := sink.Publish(ctx, event)
+		if err != nil {
+			return fmt.Errorf("outbox sink %s rejected event %s: %w", sink.Name(), event.ID, err)
+		}
+	}
+
+	return nil
+}
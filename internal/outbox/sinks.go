@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+)
+
+var ErrUnexpectedStatus = errors.New("outbox endpoint returned a non-2xx status")
+
+const EventIDHeader = "X-Greenlight-Event-Id"
+
+// WriterSink writes each event as a single line of JSON.
+type WriterSink struct {
+	name string
+	out  io.Writer
+	mu   sync.Mutex
+}
+
+func NewWriterSink(name string, out io.Writer) *WriterSink {
+	return &WriterSink{name: name, out: out}
+}
+
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink("stdout", os.Stdout)
+}
+
+func (sink *WriterSink) Name() string {
+	return sink.name
+}
+
+func (sink *WriterSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	_, err = sink.out.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends events to an NDJSON file and syncs after every write, so an event is on disk
+// before the relay marks it as published.
+type FileSink struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (sink *FileSink) Name() string {
+	return "file"
+}
+
+func (sink *FileSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	_, err = sink.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return sink.file.Sync()
+}
+
+func (sink *FileSink) Close() error {
+	return sink.file.Close()
+}
+
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url, Client: &http.Client{}}
+}
+
+func (sink *HTTPSink) Name() string {
+	return "http"
+}
+
+func (sink *HTTPSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, event.ID)
+	req.Header.Set("Idempotency-Key", event.ID)
+
+	res, err := sink.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	}
+
+	return nil
+}
+
+type SinkFunc struct {
+	SinkName string
+	Fn       func(ctx context.Context, event *data.OutboxEvent) error
+}
+
+func (sink SinkFunc) Name() string {
+	return sink.SinkName
+}
+
+func (sink SinkFunc) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	return sink.Fn(ctx, event)
+}
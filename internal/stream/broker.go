@@ -0,0 +1,194 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Yusufdot101/greenlight/internal/data"
+	"github.com/Yusufdot101/greenlight/internal/jsonlog"
+	"github.com/lib/pq"
+)
+
+const Channel = "outbox_events"
+
+type Subscription struct {
+	Events chan *data.OutboxEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Done is closed when the subscription is dropped, either because the broker is shutting down
+// or because the subscriber fell too far behind. Clients are expected to reconnect and resume
+// from the last event they saw.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+func (sub *Subscription) close() {
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// Broker fans events out to every subscriber as the outbox trigger announces them through
+// LISTEN/NOTIFY. The trigger fires when the relay publishes an event, so events arrive in
+// published sequence order.
+type Broker struct {
+	outbox       *data.OutboxModel
+	logger       *jsonlog.Logger
+	listener     *pq.Listener
+	bufferSize   int
+	mu           sync.Mutex
+	subscribers  map[*Subscription]struct{}
+	lastSequence int64
+	quit         chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+func NewBroker(dsn string, outbox *data.OutboxModel, logger *jsonlog.Logger) *Broker {
+	report := func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.PrintError(err, map[string]string{"listener": Channel})
+		}
+	}
+
+	return &Broker{
+		outbox:      outbox,
+		logger:      logger,
+		listener:    pq.NewListener(dsn, time.Second, time.Minute, report),
+		bufferSize:  64,
+		subscribers: make(map[*Subscription]struct{}),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (broker *Broker) Start() error {
+	err := broker.listener.Listen(Channel)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(broker.done)
+		broker.run()
+	}()
+
+	return nil
+}
+
+func (broker *Broker) Subscribe() *Subscription {
+	sub := &Subscription{
+		Events: make(chan *data.OutboxEvent, broker.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	broker.mu.Lock()
+	broker.subscribers[sub] = struct{}{}
+	broker.mu.Unlock()
+
+	return sub
+}
+
+func (broker *Broker) Unsubscribe(sub *Subscription) {
+	broker.mu.Lock()
+	delete(broker.subscribers, sub)
+	broker.mu.Unlock()
+
+	sub.close()
+}
+
+// Close stops listening and ends every subscription. It is safe to call more than once.
+func (broker *Broker) Close() {
+	broker.closeOnce.Do(func() {
+		close(broker.quit)
+		<-broker.done
+
+		err := broker.listener.Close()
+		if err != nil {
+			broker.logger.PrintError(err, map[string]string{"listener": Channel})
+		}
+
+		broker.mu.Lock()
+		for sub := range broker.subscribers {
+			delete(broker.subscribers, sub)
+			sub.close()
+		}
+		broker.mu.Unlock()
+	})
+}
+
+func (broker *Broker) run() {
+	for {
+		select {
+		case <-broker.quit:
+			return
+		case notification := <-broker.listener.Notify:
+			// a nil notification means the connection was re-established and anything sent
+			// while it was down has been lost
+			if notification == nil {
+				broker.catchUp()
+				continue
+			}
+
+			sequences := []int64{}
+			for notification != nil {
+				sequence, err := strconv.ParseInt(notification.Extra, 10, 64)
+				if err == nil {
+					sequences = append(sequences, sequence)
+				}
+
+				select {
+				case notification = <-broker.listener.Notify:
+				default:
+					notification = nil
+				}
+			}
+
+			events, err := broker.outbox.GetByPublishedSequence(context.Background(), sequences)
+			if err != nil {
+				broker.logger.PrintError(err, nil)
+				continue
+			}
+			broker.broadcast(events)
+		case <-time.After(90 * time.Second):
+			go broker.listener.Ping()
+		}
+	}
+}
+
+func (broker *Broker) catchUp() {
+	broker.mu.Lock()
+	lastSequence := broker.lastSequence
+	broker.mu.Unlock()
+
+	if lastSequence == 0 {
+		return
+	}
+
+	events, err := broker.outbox.EventsAfter(context.Background(), "", lastSequence, 1000)
+	if err != nil {
+		broker.logger.PrintError(err, nil)
+		return
+	}
+	broker.broadcast(events)
+}
+
+func (broker *Broker) broadcast(events []*data.OutboxEvent) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for _, event := range events {
+		broker.lastSequence = max(broker.lastSequence, event.PublishedSequence)
+
+		for sub := range broker.subscribers {
+			select {
+			case sub.Events <- event:
+			default:
+				delete(broker.subscribers, sub)
+				sub.close()
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter sends finished spans somewhere they can be looked at.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+type Config struct {
+	// BatchSize is how many finished spans are buffered before they are exported early.
+	BatchSize int
+	// FlushInterval is how often buffered spans are exported.
+	FlushInterval time.Duration
+	// OnError is called with errors returned by the exporter.
+	OnError func(err error)
+}
+
+// Tracer starts spans and batches the finished ones for its exporter. A Tracer without an
+// exporter still creates spans, so trace IDs are propagated and logged, but drops them once
+// they end.
+type Tracer struct {
+	config   Config
+	exporter Exporter
+
+	mu      sync.Mutex
+	buffer  []*Span
+	flush   chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+func NewTracer(exporter Exporter, config Config) *Tracer {
+	return &Tracer{
+		config:   config,
+		exporter: exporter,
+		flush:    make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start starts a span as a child of the span or remote span context in ctx, or as the root of a
+// new trace when there is neither, and returns a context carrying it.
+func (tracer *Tracer) Start(
+	ctx context.Context, name string, kind SpanKind, attributes ...Attribute,
+) (context.Context, *Span) {
+	span := &Span{
+		tracer:     tracer,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: slices.Clone(attributes),
+	}
+
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+		span.parent = parent.SpanID
+	} else {
+		span.sc = SpanContext{TraceID: newTraceID(), Sampled: true}
+	}
+	span.sc.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Run exports finished spans every FlushInterval, or sooner when BatchSize of them are waiting,
+// until Shutdown is called.
+func (tracer *Tracer) Run() {
+	defer close(tracer.stopped)
+
+	if tracer.exporter == nil {
+		<-tracer.quit
+		return
+	}
+
+	ticker := time.NewTicker(tracer.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tracer.quit:
+			tracer.export()
+			return
+		case <-ticker.C:
+		case <-tracer.flush:
+		}
+		tracer.export()
+	}
+}
+
+// Shutdown stops Run after it has exported the spans that have already ended.
+func (tracer *Tracer) Shutdown(ctx context.Context) error {
+	close(tracer.quit)
+
+	select {
+	case <-tracer.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (tracer *Tracer) enqueue(span *Span) {
+	if tracer.exporter == nil {
+		return
+	}
+
+	tracer.mu.Lock()
+	tracer.buffer = append(tracer.buffer, span)
+	full := len(tracer.buffer) >= tracer.config.BatchSize
+	tracer.mu.Unlock()
+
+	if full {
+		select {
+		case tracer.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (tracer *Tracer) export() {
+	tracer.mu.Lock()
+	spans := tracer.buffer
+	tracer.buffer = nil
+	tracer.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	err := tracer.exporter.Export(spans)
+	if err != nil && tracer.config.OnError != nil {
+		tracer.config.OnError(err)
+	}
+}
+
+// JSONExporter writes each batch of spans as one line of OTLP/JSON, the encoding of an
+// ExportTraceServiceRequest used by the OpenTelemetry collector's file exporter and receiver.
+type JSONExporter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	resource []Attribute
+}
+
+func NewJSONExporter(out io.Writer, resource ...Attribute) *JSONExporter {
+	return &JSONExporter{out: out, resource: resource}
+}
+
+// the OTLP/JSON messages, with 64-bit integers as strings as the protobuf JSON mapping requires
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+const otlpStatusError = 2
+
+func (exporter *JSONExporter) Export(spans []*Span) error {
+	encoded := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		encoded[i] = encodeSpan(span)
+	}
+
+	line, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes(exporter.resource)},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/Yusufdot101/greenlight"},
+			Spans: encoded,
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+
+	_, err = exporter.out.Write(append(line, '\n'))
+	return err
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	encoded := otlpSpan{
+		TraceID:           span.sc.TraceID.String(),
+		SpanID:            span.sc.SpanID.String(),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: unixNano(span.start),
+		EndTimeUnixNano:   unixNano(span.end),
+		Attributes:        encodeAttributes(span.attributes),
+	}
+	if span.parent.IsValid() {
+		encoded.ParentSpanID = span.parent.String()
+	}
+	for _, e := range span.events {
+		encoded.Events = append(encoded.Events, otlpEvent{
+			TimeUnixNano: unixNano(e.time),
+			Name:         e.name,
+			Attributes:   encodeAttributes(e.attributes),
+		})
+	}
+	if span.failed {
+		encoded.Status = &otlpStatus{Code: otlpStatusError, Message: span.statusMessage}
+	}
+
+	return encoded
+}
+
+func encodeAttributes(attributes []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, len(attributes))
+	for i, attribute := range attributes {
+		var value map[string]any
+		switch v := attribute.Value.(type) {
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": v}
+		}
+		encoded[i] = otlpAttribute{Key: attribute.Key, Value: value}
+	}
+	return encoded
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
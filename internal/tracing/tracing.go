@@ -0,0 +1,279 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext identifies a span across process boundaries, as carried by the W3C traceparent
+// header.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a traceparent header value. Values from versions later than 00 are
+// accepted as long as they start with the fields version 00 defines, as the spec requires.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+
+	version, ok := decodeHex(value[:2])
+	if !ok || version[0] == 0xff {
+		return SpanContext{}, false
+	}
+	if version[0] == 0 && len(value) != 55 {
+		return SpanContext{}, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	traceID, ok := decodeHex(value[3:35])
+	if !ok {
+		return SpanContext{}, false
+	}
+	spanID, ok := decodeHex(value[36:52])
+	if !ok {
+		return SpanContext{}, false
+	}
+	flags, ok := decodeHex(value[53:55])
+	if !ok {
+		return SpanContext{}, false
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, sc.IsValid()
+}
+
+// decodeHex accepts only lowercase hex, which is all traceparent allows.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+type SpanKind int
+
+// The span kinds, numbered as in the OTLP protocol.
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attribute { return Attribute{key, value} }
+
+func Int(key string, value int) Attribute { return Attribute{key, int64(value)} }
+
+func Int64(key string, value int64) Attribute { return Attribute{key, value} }
+
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+type event struct {
+	name       string
+	time       time.Time
+	attributes []Attribute
+}
+
+// Span is a timed operation within a trace. A nil *Span is valid and does nothing, which is
+// what Start returns when there is no trace to add the span to.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	kind   SpanKind
+	start  time.Time
+
+	mu            sync.Mutex
+	name          string
+	end           time.Time
+	attributes    []Attribute
+	events        []event
+	failed        bool
+	statusMessage string
+	ended         bool
+}
+
+func (span *Span) SpanContext() SpanContext {
+	if span == nil {
+		return SpanContext{}
+	}
+	return span.sc
+}
+
+func (span *Span) SetName(name string) {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	span.name = name
+	span.mu.Unlock()
+}
+
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	span.attributes = append(span.attributes, attributes...)
+	span.mu.Unlock()
+}
+
+// RecordError marks the span as failed and records err as an exception event. It does nothing
+// when err is nil.
+func (span *Span) RecordError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.failed = true
+	span.statusMessage = err.Error()
+	span.events = append(span.events, event{
+		name: "exception",
+		time: time.Now(),
+		attributes: []Attribute{
+			String("exception.type", fmt.Sprintf("%T", err)),
+			String("exception.message", err.Error()),
+		},
+	})
+}
+
+// SetError marks the span as failed without an error value, such as for a 5xx response.
+func (span *Span) SetError(message string) {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	span.failed = true
+	span.statusMessage = message
+	span.mu.Unlock()
+}
+
+// End finishes the span and hands it to the tracer for export. Calls after the first are
+// ignored.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.mu.Unlock()
+
+	if span.sc.Sampled {
+		span.tracer.enqueue(span)
+	}
+}
+
+type contextKey int
+
+const (
+	spanContextKey contextKey = iota
+	remoteContextKey
+)
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext sets sc, usually parsed from an incoming traceparent, as the
+// parent of the next span a Tracer starts from ctx.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteContextKey, sc)
+}
+
+// SpanContextFromContext returns the context of the current span, or of the remote parent when
+// no span has been started yet.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteContextKey).(SpanContext)
+	return sc
+}
+
+// Start starts a child of the span in ctx using the same tracer. When ctx has no span it returns
+// a nil span, so code that is sometimes called outside a trace can still call Start.
+func Start(
+	ctx context.Context, name string, kind SpanKind, attributes ...Attribute,
+) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, kind, attributes...)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
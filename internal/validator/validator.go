@@ -9,6 +9,8 @@ var EmailRX = regexp.MustCompile(
 	"^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$",
 )
 
+var LocaleRX = regexp.MustCompile("^[a-z]{2,3}(-[a-z0-9]{2,8})*$")
+
 type Validator struct {
 	Errors map[string]string
 }
@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Greenlight-Event"
+	EventIDHeader   = "X-Greenlight-Event-Id"
+	DeliveryHeader  = "X-Greenlight-Delivery"
+	TimestampHeader = "X-Greenlight-Timestamp"
+	SignatureHeader = "X-Greenlight-Signature"
+)
+
+var ErrUnexpectedStatus = errors.New("webhook receiver returned a non-2xx status")
+
+func NewSecret() (string, error) {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
+
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature sent in the SignatureHeader. It covers the timestamp as well as
+// the body so that a captured delivery cannot be replayed later with a fresh timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+type Delivery struct {
+	ID      string
+	URL     string
+	Secret  string
+	EventID string
+	Event   string
+	Body    []byte
+}
+
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		HTTP:      &http.Client{Timeout: timeout},
+		UserAgent: "Greenlight-Webhooks/1.0",
+	}
+}
+
+func (client *Client) Deliver(ctx context.Context, delivery *Delivery) (*Result, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", client.UserAgent)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Body))
+
+	start := time.Now()
+	res, err := client.HTTP.Do(req)
+	result := &Result{Duration: time.Since(start)}
+	if err != nil {
+		return result, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	result.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return result, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	}
+
+	return result, nil
+}
+
+func ValidURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
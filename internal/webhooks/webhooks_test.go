@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is an httptest webhook endpoint that answers with the given statuses in turn and
+// records every delivery it is sent.
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, func() []receivedDelivery) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []receivedDelivery
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		received = append(received, receivedDelivery{header: r.Header.Clone(), body: body})
+		status := statuses[min(len(received), len(statuses))-1]
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []receivedDelivery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedDelivery(nil), received...)
+	}
+}
+
+func TestDeliverSignature(t *testing.T) {
+	server, received := receiver(t, http.StatusNoContent)
+
+	delivery := &Delivery{
+		ID:      "17",
+		URL:     server.URL,
+		Secret:  "s3cret",
+		EventID: "3f8e2b1c",
+		Event:   "movie.created",
+		Body:    []byte(`{"id":"3f8e2b1c","type":"movie.created"}`),
+	}
+
+	result, err := NewClient(time.Second).Deliver(context.Background(), delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StatusCode != http.StatusNoContent {
+		t.Errorf("got status %d; want %d", result.StatusCode, http.StatusNoContent)
+	}
+
+	deliveries := received()
+	if len(deliveries) != 1 {
+		t.Fatalf("receiver got %d deliveries; want 1", len(deliveries))
+	}
+	got := deliveries[0]
+
+	for header, want := range map[string]string{
+		EventHeader:    "movie.created",
+		EventIDHeader:  "3f8e2b1c",
+		DeliveryHeader: "17",
+		"Content-Type": "application/json",
+	} {
+		if value := got.header.Get(header); value != want {
+			t.Errorf("got %s %q; want %q", header, value, want)
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(got.header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", TimestampHeader, err)
+	}
+	signature := got.header.Get(SignatureHeader)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		want      bool
+	}{
+		{"as delivered", "s3cret", timestamp, got.body, true},
+		{"wrong secret", "not the secret", timestamp, got.body, false},
+		{"replayed timestamp", "s3cret", timestamp + 60, got.body, false},
+		{"tampered body", "s3cret", timestamp, []byte(`{"id":"3f8e2b1c"}`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := Verify(tt.secret, tt.timestamp, tt.body, signature); ok != tt.want {
+				t.Errorf("got Verify %t; want %t", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverRetriesNon2xx(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{"accepted first time", []int{http.StatusOK}, 1},
+		{"server error then accepted", []int{http.StatusServiceUnavailable, http.StatusAccepted}, 2},
+		{"redirect then accepted", []int{http.StatusFound, http.StatusNoContent}, 2},
+		{"client error then accepted", []int{http.StatusGone, http.StatusGone, http.StatusOK}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := receiver(t, tt.statuses...)
+			client := NewClient(time.Second)
+
+			delivery := &Delivery{
+				ID:     "1",
+				URL:    server.URL,
+				Secret: "s3cret",
+				Event:  "movie.updated",
+				Body:   []byte(`{}`),
+			}
+
+			// the job queue retries a delivery for as long as Deliver returns an error
+			attempts := 0
+			for {
+				attempts++
+				result, err := client.Deliver(context.Background(), delivery)
+				if err == nil {
+					break
+				}
+				if !errors.Is(err, ErrUnexpectedStatus) {
+					t.Fatalf("got error %v; want ErrUnexpectedStatus", err)
+				}
+				if result.StatusCode != tt.statuses[attempts-1] {
+					t.Errorf("got status %d; want %d", result.StatusCode, tt.statuses[attempts-1])
+				}
+				if attempts > len(tt.statuses) {
+					t.Fatal("delivery was never accepted")
+				}
+			}
+
+			if attempts != tt.attempts {
+				t.Errorf("delivered in %d attempts; want %d", attempts, tt.attempts)
+			}
+			if n := len(received()); n != tt.attempts {
+				t.Errorf("receiver got %d deliveries; want %d", n, tt.attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code IN ('jobs:read', 'jobs:write');
+
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+    kind text NOT NULL,
+    payload jsonb NOT NULL DEFAULT '{}',
+    result jsonb,
+    status text NOT NULL DEFAULT 'pending',
+    priority integer NOT NULL DEFAULT 0,
+    attempts integer NOT NULL DEFAULT 0,
+    max_attempts integer NOT NULL DEFAULT 5,
+    run_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMP(0) with time zone,
+    completed_at TIMESTAMP(0) with time zone,
+    last_error text NOT NULL DEFAULT ''
+);
+
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK(
+    status IN ('pending', 'running', 'completed', 'dead')
+);
+
+CREATE INDEX IF NOT EXISTS jobs_ready_idx ON jobs(priority DESC, run_at, id)
+    WHERE status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs(status, kind);
+
+INSERT INTO permissions (code)
+VALUES
+    ('jobs:read'),
+    ('jobs:write');
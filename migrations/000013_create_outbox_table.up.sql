@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    event_id text UNIQUE NOT NULL,
+    event text NOT NULL,
+    aggregate text NOT NULL,
+    aggregate_id bigint NOT NULL,
+    payload jsonb NOT NULL,
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox(id) WHERE published_at IS NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id bigint NOT NULL,
+    key text NOT NULL,
+    fingerprint text NOT NULL,
+    status text NOT NULL DEFAULT 'processing',
+    response_status integer,
+    response_headers jsonb,
+    response_body bytea,
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP(0) with time zone NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS outbox_notify_trigger ON outbox;
+
+CREATE OR REPLACE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify_trigger
+AFTER INSERT ON outbox
+FOR EACH ROW EXECUTE FUNCTION outbox_notify();
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS published_seq;
+
+DROP SEQUENCE IF EXISTS outbox_published_seq;
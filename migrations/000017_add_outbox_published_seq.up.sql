@@ -0,0 +1,28 @@
+CREATE SEQUENCE IF NOT EXISTS outbox_published_seq;
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS published_seq bigint UNIQUE;
+
+UPDATE outbox SET published_seq = numbered.seq
+FROM (
+    SELECT id, row_number() OVER (ORDER BY id) AS seq
+    FROM outbox
+    WHERE published_at IS NOT NULL
+) AS numbered
+WHERE outbox.id = numbered.id;
+
+SELECT setval('outbox_published_seq', COALESCE(MAX(published_seq), 0) + 1, false) FROM outbox;
+
+DROP TRIGGER IF EXISTS outbox_notify_trigger ON outbox;
+
+CREATE OR REPLACE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.published_seq::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify_trigger
+AFTER UPDATE OF published_seq ON outbox
+FOR EACH ROW
+WHEN (OLD.published_seq IS NULL AND NEW.published_seq IS NOT NULL)
+EXECUTE FUNCTION outbox_notify();
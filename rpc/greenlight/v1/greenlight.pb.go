@@ -0,0 +1,902 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: rpc/greenlight/v1/greenlight.proto
+
+package greenlightv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Movie struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Runtime       int32                  `protobuf:"varint,3,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Year          int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	Genres        []string               `protobuf:"bytes,5,rep,name=genres,proto3" json:"genres,omitempty"`
+	Version       int32                  `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Movie) Reset() {
+	*x = Movie{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Movie) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Movie) ProtoMessage() {}
+
+func (x *Movie) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Movie.ProtoReflect.Descriptor instead.
+func (*Movie) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Movie) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Movie) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Movie) GetRuntime() int32 {
+	if x != nil {
+		return x.Runtime
+	}
+	return 0
+}
+
+func (x *Movie) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *Movie) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *Movie) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Movie) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type Genres struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Genres) Reset() {
+	*x = Genres{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Genres) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Genres) ProtoMessage() {}
+
+func (x *Genres) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Genres.ProtoReflect.Descriptor instead.
+func (*Genres) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Genres) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type CreateMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Runtime       int32                  `protobuf:"varint,2,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Year          int32                  `protobuf:"varint,3,opt,name=year,proto3" json:"year,omitempty"`
+	Genres        []string               `protobuf:"bytes,4,rep,name=genres,proto3" json:"genres,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateMovieRequest) Reset() {
+	*x = CreateMovieRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateMovieRequest) ProtoMessage() {}
+
+func (x *CreateMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateMovieRequest.ProtoReflect.Descriptor instead.
+func (*CreateMovieRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateMovieRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateMovieRequest) GetRuntime() int32 {
+	if x != nil {
+		return x.Runtime
+	}
+	return 0
+}
+
+func (x *CreateMovieRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *CreateMovieRequest) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+type GetMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMovieRequest) Reset() {
+	*x = GetMovieRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMovieRequest) ProtoMessage() {}
+
+func (x *GetMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMovieRequest.ProtoReflect.Descriptor instead.
+func (*GetMovieRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// UpdateMovieRequest only changes the fields that are set. When version is set the update is
+// rejected with ABORTED unless it matches the current version of the movie.
+type UpdateMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       *int32                 `protobuf:"varint,2,opt,name=version,proto3,oneof" json:"version,omitempty"`
+	Title         *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Runtime       *int32                 `protobuf:"varint,4,opt,name=runtime,proto3,oneof" json:"runtime,omitempty"`
+	Year          *int32                 `protobuf:"varint,5,opt,name=year,proto3,oneof" json:"year,omitempty"`
+	Genres        *Genres                `protobuf:"bytes,6,opt,name=genres,proto3" json:"genres,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMovieRequest) Reset() {
+	*x = UpdateMovieRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMovieRequest) ProtoMessage() {}
+
+func (x *UpdateMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMovieRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMovieRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpdateMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetVersion() int32 {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *UpdateMovieRequest) GetRuntime() int32 {
+	if x != nil && x.Runtime != nil {
+		return *x.Runtime
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetYear() int32 {
+	if x != nil && x.Year != nil {
+		return *x.Year
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetGenres() *Genres {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+type DeleteMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMovieRequest) Reset() {
+	*x = DeleteMovieRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMovieRequest) ProtoMessage() {}
+
+func (x *DeleteMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMovieRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMovieRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteMovieResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMovieResponse) Reset() {
+	*x = DeleteMovieResponse{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMovieResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMovieResponse) ProtoMessage() {}
+
+func (x *DeleteMovieResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMovieResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMovieResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{6}
+}
+
+type ListMoviesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Genres        []string               `protobuf:"bytes,2,rep,name=genres,proto3" json:"genres,omitempty"`
+	YearMin       int32                  `protobuf:"varint,3,opt,name=year_min,json=yearMin,proto3" json:"year_min,omitempty"`
+	YearMax       int32                  `protobuf:"varint,4,opt,name=year_max,json=yearMax,proto3" json:"year_max,omitempty"`
+	Page          int32                  `protobuf:"varint,5,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,6,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Sort          string                 `protobuf:"bytes,7,opt,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMoviesRequest) Reset() {
+	*x = ListMoviesRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMoviesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMoviesRequest) ProtoMessage() {}
+
+func (x *ListMoviesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMoviesRequest.ProtoReflect.Descriptor instead.
+func (*ListMoviesRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListMoviesRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ListMoviesRequest) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetYearMin() int32 {
+	if x != nil {
+		return x.YearMin
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetYearMax() int32 {
+	if x != nil {
+		return x.YearMax
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CurrentPage   int32                  `protobuf:"varint,1,opt,name=current_page,json=currentPage,proto3" json:"current_page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	TotalRecords  int32                  `protobuf:"varint,3,opt,name=total_records,json=totalRecords,proto3" json:"total_records,omitempty"`
+	LastPage      int32                  `protobuf:"varint,4,opt,name=last_page,json=lastPage,proto3" json:"last_page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Metadata) GetCurrentPage() int32 {
+	if x != nil {
+		return x.CurrentPage
+	}
+	return 0
+}
+
+func (x *Metadata) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *Metadata) GetTotalRecords() int32 {
+	if x != nil {
+		return x.TotalRecords
+	}
+	return 0
+}
+
+func (x *Metadata) GetLastPage() int32 {
+	if x != nil {
+		return x.LastPage
+	}
+	return 0
+}
+
+type ListMoviesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Movies        []*Movie               `protobuf:"bytes,1,rep,name=movies,proto3" json:"movies,omitempty"`
+	Metadata      *Metadata              `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMoviesResponse) Reset() {
+	*x = ListMoviesResponse{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMoviesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMoviesResponse) ProtoMessage() {}
+
+func (x *ListMoviesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMoviesResponse.ProtoReflect.Descriptor instead.
+func (*ListMoviesResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListMoviesResponse) GetMovies() []*Movie {
+	if x != nil {
+		return x.Movies
+	}
+	return nil
+}
+
+func (x *ListMoviesResponse) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type CreateAuthenticationTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAuthenticationTokenRequest) Reset() {
+	*x = CreateAuthenticationTokenRequest{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAuthenticationTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAuthenticationTokenRequest) ProtoMessage() {}
+
+func (x *CreateAuthenticationTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAuthenticationTokenRequest.ProtoReflect.Descriptor instead.
+func (*CreateAuthenticationTokenRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CreateAuthenticationTokenRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *CreateAuthenticationTokenRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type AuthenticationToken struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Expiry        *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthenticationToken) Reset() {
+	*x = AuthenticationToken{}
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticationToken) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticationToken) ProtoMessage() {}
+
+func (x *AuthenticationToken) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_greenlight_v1_greenlight_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticationToken.ProtoReflect.Descriptor instead.
+func (*AuthenticationToken) Descriptor() ([]byte, []int) {
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AuthenticationToken) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *AuthenticationToken) GetExpiry() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Expiry
+	}
+	return nil
+}
+
+var File_rpc_greenlight_v1_greenlight_proto protoreflect.FileDescriptor
+
+const file_rpc_greenlight_v1_greenlight_proto_rawDesc = "" +
+	"\n" +
+	"\"rpc/greenlight/v1/greenlight.proto\x12\rgreenlight.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc8\x01\n" +
+	"\x05Movie\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x18\n" +
+	"\aruntime\x18\x03 \x01(\x05R\aruntime\x12\x12\n" +
+	"\x04year\x18\x04 \x01(\x05R\x04year\x12\x16\n" +
+	"\x06genres\x18\x05 \x03(\tR\x06genres\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversion\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\" \n" +
+	"\x06Genres\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"p\n" +
+	"\x12CreateMovieRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x18\n" +
+	"\aruntime\x18\x02 \x01(\x05R\aruntime\x12\x12\n" +
+	"\x04year\x18\x03 \x01(\x05R\x04year\x12\x16\n" +
+	"\x06genres\x18\x04 \x03(\tR\x06genres\"!\n" +
+	"\x0fGetMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\xf0\x01\n" +
+	"\x12UpdateMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1d\n" +
+	"\aversion\x18\x02 \x01(\x05H\x00R\aversion\x88\x01\x01\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x01R\x05title\x88\x01\x01\x12\x1d\n" +
+	"\aruntime\x18\x04 \x01(\x05H\x02R\aruntime\x88\x01\x01\x12\x17\n" +
+	"\x04year\x18\x05 \x01(\x05H\x03R\x04year\x88\x01\x01\x12-\n" +
+	"\x06genres\x18\x06 \x01(\v2\x15.greenlight.v1.GenresR\x06genresB\n" +
+	"\n" +
+	"\b_versionB\b\n" +
+	"\x06_titleB\n" +
+	"\n" +
+	"\b_runtimeB\a\n" +
+	"\x05_year\"$\n" +
+	"\x12DeleteMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x15\n" +
+	"\x13DeleteMovieResponse\"\xbc\x01\n" +
+	"\x11ListMoviesRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x16\n" +
+	"\x06genres\x18\x02 \x03(\tR\x06genres\x12\x19\n" +
+	"\byear_min\x18\x03 \x01(\x05R\ayearMin\x12\x19\n" +
+	"\byear_max\x18\x04 \x01(\x05R\ayearMax\x12\x12\n" +
+	"\x04page\x18\x05 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x06 \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04sort\x18\a \x01(\tR\x04sort\"\x8c\x01\n" +
+	"\bMetadata\x12!\n" +
+	"\fcurrent_page\x18\x01 \x01(\x05R\vcurrentPage\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12#\n" +
+	"\rtotal_records\x18\x03 \x01(\x05R\ftotalRecords\x12\x1b\n" +
+	"\tlast_page\x18\x04 \x01(\x05R\blastPage\"w\n" +
+	"\x12ListMoviesResponse\x12,\n" +
+	"\x06movies\x18\x01 \x03(\v2\x14.greenlight.v1.MovieR\x06movies\x123\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x17.greenlight.v1.MetadataR\bmetadata\"T\n" +
+	" CreateAuthenticationTokenRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"_\n" +
+	"\x13AuthenticationToken\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x122\n" +
+	"\x06expiry\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x06expiry2\x89\x03\n" +
+	"\fMovieService\x12F\n" +
+	"\vCreateMovie\x12!.greenlight.v1.CreateMovieRequest\x1a\x14.greenlight.v1.Movie\x12@\n" +
+	"\bGetMovie\x12\x1e.greenlight.v1.GetMovieRequest\x1a\x14.greenlight.v1.Movie\x12F\n" +
+	"\vUpdateMovie\x12!.greenlight.v1.UpdateMovieRequest\x1a\x14.greenlight.v1.Movie\x12T\n" +
+	"\vDeleteMovie\x12!.greenlight.v1.DeleteMovieRequest\x1a\".greenlight.v1.DeleteMovieResponse\x12Q\n" +
+	"\n" +
+	"ListMovies\x12 .greenlight.v1.ListMoviesRequest\x1a!.greenlight.v1.ListMoviesResponse2\x80\x01\n" +
+	"\fTokenService\x12p\n" +
+	"\x19CreateAuthenticationToken\x12/.greenlight.v1.CreateAuthenticationTokenRequest\x1a\".greenlight.v1.AuthenticationTokenBBZ@github.com/Yusufdot101/greenlight/rpc/greenlight/v1;greenlightv1b\x06proto3"
+
+var (
+	file_rpc_greenlight_v1_greenlight_proto_rawDescOnce sync.Once
+	file_rpc_greenlight_v1_greenlight_proto_rawDescData []byte
+)
+
+func file_rpc_greenlight_v1_greenlight_proto_rawDescGZIP() []byte {
+	file_rpc_greenlight_v1_greenlight_proto_rawDescOnce.Do(func() {
+		file_rpc_greenlight_v1_greenlight_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rpc_greenlight_v1_greenlight_proto_rawDesc), len(file_rpc_greenlight_v1_greenlight_proto_rawDesc)))
+	})
+	return file_rpc_greenlight_v1_greenlight_proto_rawDescData
+}
+
+var file_rpc_greenlight_v1_greenlight_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_rpc_greenlight_v1_greenlight_proto_goTypes = []any{
+	(*Movie)(nil),                            // 0: greenlight.v1.Movie
+	(*Genres)(nil),                           // 1: greenlight.v1.Genres
+	(*CreateMovieRequest)(nil),               // 2: greenlight.v1.CreateMovieRequest
+	(*GetMovieRequest)(nil),                  // 3: greenlight.v1.GetMovieRequest
+	(*UpdateMovieRequest)(nil),               // 4: greenlight.v1.UpdateMovieRequest
+	(*DeleteMovieRequest)(nil),               // 5: greenlight.v1.DeleteMovieRequest
+	(*DeleteMovieResponse)(nil),              // 6: greenlight.v1.DeleteMovieResponse
+	(*ListMoviesRequest)(nil),                // 7: greenlight.v1.ListMoviesRequest
+	(*Metadata)(nil),                         // 8: greenlight.v1.Metadata
+	(*ListMoviesResponse)(nil),               // 9: greenlight.v1.ListMoviesResponse
+	(*CreateAuthenticationTokenRequest)(nil), // 10: greenlight.v1.CreateAuthenticationTokenRequest
+	(*AuthenticationToken)(nil),              // 11: greenlight.v1.AuthenticationToken
+	(*timestamppb.Timestamp)(nil),            // 12: google.protobuf.Timestamp
+}
+var file_rpc_greenlight_v1_greenlight_proto_depIdxs = []int32{
+	12, // 0: greenlight.v1.Movie.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 1: greenlight.v1.UpdateMovieRequest.genres:type_name -> greenlight.v1.Genres
+	0,  // 2: greenlight.v1.ListMoviesResponse.movies:type_name -> greenlight.v1.Movie
+	8,  // 3: greenlight.v1.ListMoviesResponse.metadata:type_name -> greenlight.v1.Metadata
+	12, // 4: greenlight.v1.AuthenticationToken.expiry:type_name -> google.protobuf.Timestamp
+	2,  // 5: greenlight.v1.MovieService.CreateMovie:input_type -> greenlight.v1.CreateMovieRequest
+	3,  // 6: greenlight.v1.MovieService.GetMovie:input_type -> greenlight.v1.GetMovieRequest
+	4,  // 7: greenlight.v1.MovieService.UpdateMovie:input_type -> greenlight.v1.UpdateMovieRequest
+	5,  // 8: greenlight.v1.MovieService.DeleteMovie:input_type -> greenlight.v1.DeleteMovieRequest
+	7,  // 9: greenlight.v1.MovieService.ListMovies:input_type -> greenlight.v1.ListMoviesRequest
+	10, // 10: greenlight.v1.TokenService.CreateAuthenticationToken:input_type -> greenlight.v1.CreateAuthenticationTokenRequest
+	0,  // 11: greenlight.v1.MovieService.CreateMovie:output_type -> greenlight.v1.Movie
+	0,  // 12: greenlight.v1.MovieService.GetMovie:output_type -> greenlight.v1.Movie
+	0,  // 13: greenlight.v1.MovieService.UpdateMovie:output_type -> greenlight.v1.Movie
+	6,  // 14: greenlight.v1.MovieService.DeleteMovie:output_type -> greenlight.v1.DeleteMovieResponse
+	9,  // 15: greenlight.v1.MovieService.ListMovies:output_type -> greenlight.v1.ListMoviesResponse
+	11, // 16: greenlight.v1.TokenService.CreateAuthenticationToken:output_type -> greenlight.v1.AuthenticationToken
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_rpc_greenlight_v1_greenlight_proto_init() }
+func file_rpc_greenlight_v1_greenlight_proto_init() {
+	if File_rpc_greenlight_v1_greenlight_proto != nil {
+		return
+	}
+	file_rpc_greenlight_v1_greenlight_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rpc_greenlight_v1_greenlight_proto_rawDesc), len(file_rpc_greenlight_v1_greenlight_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_rpc_greenlight_v1_greenlight_proto_goTypes,
+		DependencyIndexes: file_rpc_greenlight_v1_greenlight_proto_depIdxs,
+		MessageInfos:      file_rpc_greenlight_v1_greenlight_proto_msgTypes,
+	}.Build()
+	File_rpc_greenlight_v1_greenlight_proto = out.File
+	file_rpc_greenlight_v1_greenlight_proto_goTypes = nil
+	file_rpc_greenlight_v1_greenlight_proto_depIdxs = nil
+}